@@ -0,0 +1,6 @@
+data "k3d_k3s_versions" "channels" {}
+
+resource "k3d_cluster" "cluster" {
+  name  = "foo"
+  image = data.k3d_k3s_versions.channels.channels["stable"].image
+}
@@ -1,3 +1,15 @@
 data "k3d_nodes" "foo" {
   cluster_name = "foo"
 }
+
+data "k3d_nodes" "foo_agents" {
+  cluster_name = "foo"
+  roles        = ["agent"]
+}
+
+check "nodes_running" {
+  assert {
+    condition     = alltrue([for node in data.k3d_nodes.foo.nodes : node.running])
+    error_message = "Not all nodes of the cluster are running"
+  }
+}
@@ -0,0 +1,16 @@
+resource "k3d_cluster" "cluster" {
+  name = "foo"
+}
+
+resource "k3d_cluster_ready" "cluster" {
+  cluster_name = k3d_cluster.cluster.name
+  timeout      = "3m"
+  deployments  = ["coredns", "local-path-provisioner", "metrics-server"]
+}
+
+resource "k3d_helm_chart" "podinfo" {
+  cluster_name = k3d_cluster_ready.cluster.cluster_name
+  name         = "podinfo"
+  chart        = "podinfo"
+  repo         = "https://stefanprodan.github.io/podinfo"
+}
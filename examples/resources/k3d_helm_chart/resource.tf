@@ -0,0 +1,17 @@
+resource "k3d_cluster" "cluster" {
+  name = "foo"
+}
+
+resource "k3d_helm_chart" "podinfo" {
+  cluster_name     = k3d_cluster.cluster.name
+  name             = "podinfo"
+  chart            = "podinfo"
+  repo             = "https://stefanprodan.github.io/podinfo"
+  version          = "6.5.4"
+  target_namespace = "podinfo"
+  create_namespace = true
+
+  values = yamlencode({
+    replicaCount = 2
+  })
+}
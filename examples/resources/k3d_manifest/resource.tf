@@ -0,0 +1,14 @@
+resource "k3d_cluster" "cluster" {
+  name = "foo"
+}
+
+resource "k3d_manifest" "namespace" {
+  cluster_name = k3d_cluster.cluster.name
+  name         = "apps-namespace"
+  content      = <<-EOT
+    apiVersion: v1
+    kind: Namespace
+    metadata:
+      name: apps
+  EOT
+}
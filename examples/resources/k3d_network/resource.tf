@@ -0,0 +1,9 @@
+resource "k3d_network" "shared" {
+  name   = "shared"
+  subnet = "172.29.0.0/16"
+}
+
+resource "k3d_cluster" "cluster" {
+  name    = "foo"
+  network = k3d_network.shared.name
+}
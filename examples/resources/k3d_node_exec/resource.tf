@@ -0,0 +1,19 @@
+variable "nginx_version" {
+  type    = string
+  default = "1.25"
+}
+
+resource "k3d_cluster" "cluster" {
+  name   = "foo"
+  agents = 2
+}
+
+resource "k3d_node_exec" "pull_images" {
+  cluster_name = k3d_cluster.cluster.name
+  roles        = ["agent"]
+  command      = ["ctr", "images", "pull", "docker.io/library/nginx:${var.nginx_version}"]
+
+  triggers = {
+    nginx_version = var.nginx_version
+  }
+}
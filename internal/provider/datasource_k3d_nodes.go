@@ -3,20 +3,27 @@ package provider
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"strconv"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 
-	client "github.com/k3d-io/k3d/v5/pkg/client"
-	"github.com/k3d-io/k3d/v5/pkg/runtimes"
+	k3dtypes "github.com/k3d-io/k3d/v5/pkg/types"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces
-var _ datasource.DataSource = k3dNodesDataSource{}
+var _ datasource.DataSource = &k3dNodesDataSource{}
+var _ datasource.DataSourceWithConfigure = &k3dNodesDataSource{}
 
 var portBindingType = types.ObjectType{
 	AttrTypes: map[string]attr.Type{
@@ -27,9 +34,13 @@ var portBindingType = types.ObjectType{
 }
 
 type k3dNodesData struct {
-	ClusterName types.String       `tfsdk:"cluster_name"`
-	Id          types.String       `tfsdk:"id"`
-	Nodes       map[string]k3dNode `tfsdk:"nodes"`
+	ClusterName         types.String        `tfsdk:"cluster_name"`
+	Roles               []string            `tfsdk:"roles"`
+	NameRegex           types.String        `tfsdk:"name_regex"`
+	Labels              map[string]string   `tfsdk:"labels"`
+	Id                  types.String        `tfsdk:"id"`
+	Nodes               map[string]k3dNode  `tfsdk:"nodes"`
+	LoadbalancerPortMap map[string][]string `tfsdk:"loadbalancer_port_map"`
 }
 
 type k3dNode struct {
@@ -40,6 +51,11 @@ type k3dNode struct {
 	NodeLabels    map[string]string `tfsdk:"node_labels"`
 	Networks      []string          `tfsdk:"networks"`
 	IP            string            `tfsdk:"ip"`
+	State         string            `tfsdk:"state"`
+	Status        string            `tfsdk:"status"`
+	Running       bool              `tfsdk:"running"`
+	Created       string            `tfsdk:"created"`
+	Memory        string            `tfsdk:"memory"`
 }
 
 type k3dPort struct {
@@ -49,10 +65,29 @@ type k3dPort struct {
 }
 
 type k3dNodesDataSource struct {
+	runtime k3dRuntime
 }
 
 func NewNodesDataSource() datasource.DataSource {
-	return k3dNodesDataSource{}
+	return &k3dNodesDataSource{}
+}
+
+func (d *k3dNodesDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// the provider is not configured yet during validation
+	if req.ProviderData == nil {
+		return
+	}
+
+	rt, ok := req.ProviderData.(k3dRuntime)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected a k3d runtime, got %T. This is always a bug in the provider code and should be reported to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.runtime = rt
 }
 
 func (d k3dNodesDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
@@ -65,21 +100,40 @@ func (d k3dNodesDataSource) Read(ctx context.Context, req datasource.ReadRequest
 		return
 	}
 
-	fmt.Println("Reading list of existing K3d nodes")
-	nodes, err := client.NodeList(ctx, runtimes.SelectedRuntime)
+	filter := k3dNodeFilter{
+		Cluster: data.ClusterName.ValueString(),
+		Roles:   data.Roles,
+		Labels:  data.Labels,
+	}
+
+	if !data.NameRegex.IsNull() {
+		var err error
+		filter.Name, err = regexp.Compile(data.NameRegex.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("name_regex"), "Invalid name_regex", err.Error())
+			return
+		}
+	}
+
+	if filter.Cluster != "" {
+		ctx = tflog.SetField(ctx, "cluster", filter.Cluster)
+	}
+
+	tflog.Debug(ctx, "reading list of existing K3d nodes")
+	nodes, err := d.runtime.NodeList(ctx)
 	if err != nil {
 		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Failed to list K3d nodes", err.Error()))
 		return
 	}
 
-	newNodes := make(map[string]k3dNode)
-	for _, node := range nodes {
-		// filter out nodes for other K3D clusters
-		cluster, ok := node.RuntimeLabels["k3d.cluster"]
-		if !ok || cluster != data.ClusterName.ValueString() {
-			continue
-		}
+	statuses, err := d.runtime.NodeStatuses(ctx)
+	if err != nil {
+		resp.Diagnostics.Append(diag.NewWarningDiagnostic("Failed to read the status of the nodes", err.Error()))
+	}
 
+	newNodes := make(map[string]k3dNode)
+	portMap := make(map[string][]string)
+	for _, node := range filter.Apply(nodes) {
 		var ports []k3dPort
 
 		for port, bindings := range node.Ports {
@@ -97,6 +151,27 @@ func (d k3dNodesDataSource) Read(ctx context.Context, req datasource.ReadRequest
 			}
 		}
 
+		// the port maps of the load balancers of different clusters would
+		// clash, so they are only read for a single cluster
+		if node.Role == k3dtypes.LoadBalancerRole && filter.Cluster != "" {
+			lbConfig, err := d.runtime.LoadbalancerConfig(ctx, &k3dtypes.Cluster{
+				Name:               filter.Cluster,
+				ServerLoadBalancer: &k3dtypes.Loadbalancer{Node: node},
+			})
+			if err != nil {
+				resp.Diagnostics.Append(diag.NewWarningDiagnostic("Failed to read the load balancer configuration", err.Error()))
+			} else {
+				for port, targets := range lbConfig.Ports {
+					portMap[port] = targets
+				}
+			}
+		}
+
+		status, ok := statuses[node.Name]
+		if !ok {
+			status = node.State.Status
+		}
+
 		newNodes[node.Name] = k3dNode{
 			Name:          node.Name,
 			Role:          string(node.Role),
@@ -105,13 +180,21 @@ func (d k3dNodesDataSource) Read(ctx context.Context, req datasource.ReadRequest
 			NodeLabels:    node.K3sNodeLabels,
 			Networks:      node.Networks,
 			IP:            node.IP.IP.String(),
+			State:         node.State.Status,
+			Status:        status,
+			Running:       node.State.Running,
+			Created:       node.Created,
+			Memory:        node.Memory,
 		}
+
 	}
 
-	// For the purposes of this example code, hardcoding a response value to
-	// save into the Terraform state.
 	data.Id = data.ClusterName
+	if data.ClusterName.IsNull() {
+		data.Id = types.StringValue("*")
+	}
 	data.Nodes = newNodes
+	data.LoadbalancerPortMap = portMap
 
 	diags = resp.State.Set(ctx, &data)
 	resp.Diagnostics.Append(diags...)
@@ -127,14 +210,54 @@ func (t k3dNodesDataSource) Schema(ctx context.Context, req datasource.SchemaReq
 		MarkdownDescription: "K3d Cluster Node Listing Data Source",
 		Attributes: map[string]schema.Attribute{
 			"cluster_name": schema.StringAttribute{
-				MarkdownDescription: "Name of the K3D cluster for which to retrieve node information",
-				Required:            true,
+				MarkdownDescription: "Name of the K3D cluster for which to retrieve node information. If unset, the nodes of all clusters as well as nodes that are not part of any cluster (e.g. registries) are listed.",
+				Optional:            true,
+			},
+			"roles": schema.ListAttribute{
+				MarkdownDescription: "Only list nodes with one of the roles (`server`, `agent`, `loadbalancer` or `registry`)",
+				Optional:            true,
+				ElementType:         types.StringType,
+				Validators: []validator.List{
+					listvalidator.ValueStringsAre(
+						stringvalidator.OneOf(
+							string(k3dtypes.ServerRole),
+							string(k3dtypes.AgentRole),
+							string(k3dtypes.LoadBalancerRole),
+							string(k3dtypes.RegistryRole),
+						),
+					),
+				},
+			},
+			"name_regex": schema.StringAttribute{
+				MarkdownDescription: "Only list nodes whose name matches the regular expression",
+				Optional:            true,
+				Validators: []validator.String{
+					validateRegex,
+				},
+			},
+			"labels": schema.MapAttribute{
+				MarkdownDescription: "Only list nodes that have all of the runtime labels with the given values. k3d only reports the runtime labels prefixed with `k3d` (e.g. `k3d.cluster`), so other labels cannot be matched.",
+				Optional:            true,
+				ElementType:         types.StringType,
+				Validators: []validator.Map{
+					mapvalidator.KeysAre(
+						stringvalidator.RegexMatches(
+							regexp.MustCompile(`^k3d`),
+							"must be a runtime label reported by k3d, which are prefixed with k3d",
+						),
+					),
+				},
 			},
 			"id": schema.StringAttribute{
-				MarkdownDescription: "Unique cluster identifier",
+				MarkdownDescription: "Unique cluster identifier, or `*` if `cluster_name` is unset",
 				Computed:            true,
 			},
 
+			"loadbalancer_port_map": schema.MapAttribute{
+				MarkdownDescription: "Map of the ports proxied by the cluster's load balancer (e.g. `6443.tcp`) to the names of the nodes they are forwarded to. Only populated when `cluster_name` is set.",
+				Computed:            true,
+				ElementType:         types.ListType{ElemType: types.StringType},
+			},
 			"nodes": schema.MapNestedAttribute{
 				MarkdownDescription: "Map of node names to node information",
 				Computed:            true,
@@ -172,9 +295,73 @@ func (t k3dNodesDataSource) Schema(ctx context.Context, req datasource.SchemaReq
 							MarkdownDescription: "The IP address of the node's container",
 							Computed:            true,
 						},
+						"state": schema.StringAttribute{
+							MarkdownDescription: "The state of the node's container (e.g. `running`, `restarting` or `exited`)",
+							Computed:            true,
+						},
+						"status": schema.StringAttribute{
+							MarkdownDescription: "Human readable status of the node's container including its uptime and health (e.g. `Up 5 minutes`)",
+							Computed:            true,
+						},
+						"running": schema.BoolAttribute{
+							MarkdownDescription: "Whether the node's container is running",
+							Computed:            true,
+						},
+						"created": schema.StringAttribute{
+							MarkdownDescription: "Time at which the node's container was created in RFC 3339 format",
+							Computed:            true,
+						},
+						"memory": schema.StringAttribute{
+							MarkdownDescription: "Memory limit of the node (e.g. `1GiB`). Empty if the memory is not limited.",
+							Computed:            true,
+						},
 					},
 				},
 			},
 		},
 	}
 }
+
+// k3dNodeFilter selects nodes by cluster, role, name and runtime labels. Zero
+// valued fields match any node.
+type k3dNodeFilter struct {
+	Cluster string
+	Roles   []string
+	Name    *regexp.Regexp
+	Labels  map[string]string
+}
+
+// Apply returns the nodes matching the filter.
+func (f k3dNodeFilter) Apply(nodes []*k3dtypes.Node) []*k3dtypes.Node {
+	var matched []*k3dtypes.Node
+	for _, node := range nodes {
+		if f.Matches(node) {
+			matched = append(matched, node)
+		}
+	}
+
+	return matched
+}
+
+// Matches returns whether the node matches all criteria of the filter.
+func (f k3dNodeFilter) Matches(node *k3dtypes.Node) bool {
+	if f.Cluster != "" && node.RuntimeLabels[k3dtypes.LabelClusterName] != f.Cluster {
+		return false
+	}
+
+	if len(f.Roles) > 0 && !containsString(f.Roles, string(node.Role)) {
+		return false
+	}
+
+	if f.Name != nil && !f.Name.MatchString(node.Name) {
+		return false
+	}
+
+	for k, v := range f.Labels {
+		if value, ok := node.RuntimeLabels[k]; !ok || value != v {
+			return false
+		}
+	}
+
+	return true
+}
@@ -1,45 +1,201 @@
 package provider
 
 import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	k3dtypes "github.com/k3d-io/k3d/v5/pkg/types"
 )
 
+func TestK3dNodeFilter(t *testing.T) {
+	nodes := []*k3dtypes.Node{
+		{Name: "k3d-foo-server-0", Role: k3dtypes.ServerRole, RuntimeLabels: map[string]string{"k3d.cluster": "foo", "k3d.role": "server"}},
+		{Name: "k3d-foo-agent-0", Role: k3dtypes.AgentRole, RuntimeLabels: map[string]string{"k3d.cluster": "foo", "k3d.role": "agent"}},
+		{Name: "k3d-foo-serverlb", Role: k3dtypes.LoadBalancerRole, RuntimeLabels: map[string]string{"k3d.cluster": "foo", "k3d.role": "loadbalancer"}},
+		{Name: "k3d-bar-server-0", Role: k3dtypes.ServerRole, RuntimeLabels: map[string]string{"k3d.cluster": "bar", "k3d.role": "server"}},
+		{Name: "k3d-registry", Role: k3dtypes.RegistryRole, RuntimeLabels: map[string]string{"k3d.role": "registry"}},
+	}
+
+	cases := map[string]struct {
+		filter   k3dNodeFilter
+		expected []string
+	}{
+		"all": {
+			expected: []string{"k3d-bar-server-0", "k3d-foo-agent-0", "k3d-foo-server-0", "k3d-foo-serverlb", "k3d-registry"},
+		},
+		"cluster": {
+			filter:   k3dNodeFilter{Cluster: "foo"},
+			expected: []string{"k3d-foo-agent-0", "k3d-foo-server-0", "k3d-foo-serverlb"},
+		},
+		"roles": {
+			filter:   k3dNodeFilter{Roles: []string{"server", "registry"}},
+			expected: []string{"k3d-bar-server-0", "k3d-foo-server-0", "k3d-registry"},
+		},
+		"name regex": {
+			filter:   k3dNodeFilter{Name: regexp.MustCompile(`-(agent|server)-\d+$`)},
+			expected: []string{"k3d-bar-server-0", "k3d-foo-agent-0", "k3d-foo-server-0"},
+		},
+		"labels": {
+			filter:   k3dNodeFilter{Labels: map[string]string{"k3d.role": "loadbalancer"}},
+			expected: []string{"k3d-foo-serverlb"},
+		},
+		"combined": {
+			filter: k3dNodeFilter{
+				Cluster: "foo",
+				Roles:   []string{"server", "agent"},
+				Name:    regexp.MustCompile(`agent`),
+			},
+			expected: []string{"k3d-foo-agent-0"},
+		},
+		"missing label": {
+			filter: k3dNodeFilter{Labels: map[string]string{"purpose": "ci"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var actual []string
+			for _, node := range tc.filter.Apply(nodes) {
+				actual = append(actual, node.Name)
+			}
+			sort.Strings(actual)
+
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("matched %v, expected %v", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestK3dNodesDataSource(t *testing.T) {
+	rt := newFakeRuntime()
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:                 func() { testUnitPreCheck(t) },
+		ProtoV6ProviderFactories: testUnitProtoV6ProviderFactories(rt),
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitNodesDataSourceConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.k3d_nodes.test", "id", "unit-test"),
+					resource.TestCheckResourceAttr("data.k3d_nodes.test", "nodes.%", "3"),
+					resource.TestCheckResourceAttr("data.k3d_nodes.test", "nodes.k3d-unit-test-server-0.role", "server"),
+					resource.TestCheckResourceAttr("data.k3d_nodes.test", "nodes.k3d-unit-test-server-0.networks.0", "k3d-unit-test"),
+					resource.TestCheckResourceAttr("data.k3d_nodes.test", "nodes.k3d-unit-test-server-0.running", "true"),
+					resource.TestCheckResourceAttr("data.k3d_nodes.test", "nodes.k3d-unit-test-server-0.status", "Up 5 minutes"),
+					resource.TestCheckResourceAttr("data.k3d_nodes.test", "nodes.k3d-unit-test-agent-0.role", "agent"),
+					resource.TestCheckTypeSetElemNestedAttrs("data.k3d_nodes.test", "nodes.k3d-unit-test-serverlb.ports.*", map[string]string{"port": "6443", "host_port": "6550"}),
+					resource.TestCheckResourceAttr("data.k3d_nodes.test", "loadbalancer_port_map.6443.tcp.0", "k3d-unit-test-server-0"),
+					resource.TestCheckResourceAttr("data.k3d_nodes.agents", "nodes.%", "1"),
+				),
+			},
+		},
+	})
+}
+
+func TestK3dNodesDataSource_invalidLabels(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:                 func() { testUnitPreCheck(t) },
+		ProtoV6ProviderFactories: testUnitProtoV6ProviderFactories(newFakeRuntime()),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+data "k3d_nodes" "test" {
+  labels = {
+    purpose = "ci"
+  }
+}
+`,
+				ExpectError: regexp.MustCompile(`must be a runtime label reported by k3d`),
+			},
+		},
+	})
+}
+
 func TestAccK3dNodesDataSource(t *testing.T) {
+	name := testAccRandomName("nodes")
+	node := "nodes.k3d-" + name
+
 	resource.Test(t, resource.TestCase{
 		PreCheck:                 func() { testAccPreCheck(t) },
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
 		Steps: []resource.TestStep{
 			// Read testing
 			{
-				Config: testAccExampleDataSourceConfig,
+				Config: testAccExampleDataSourceConfig(name, testAccRandomPort()),
 				Check: resource.ComposeAggregateTestCheckFunc(
-					resource.TestCheckResourceAttr("data.k3d_nodes.test", "cluster_name", "test"),
-					resource.TestCheckResourceAttr("data.k3d_nodes.test", "id", "test"),
-					resource.TestCheckResourceAttr("data.k3d_nodes.test", "nodes.k3d-test-server-0.name", "k3d-test-server-0"),
-					resource.TestCheckResourceAttr("data.k3d_nodes.test", "nodes.k3d-test-server-0.role", "server"),
-					resource.TestCheckResourceAttrSet("data.k3d_nodes.test", "nodes.k3d-test-server-0.runtime_labels.%"),
-					resource.TestCheckResourceAttr("data.k3d_nodes.test", "nodes.k3d-test-server-0.networks.0", "k3d-test"),
-					resource.TestCheckResourceAttrSet("data.k3d_nodes.test", "nodes.k3d-test-server-0.ip"),
-					resource.TestCheckResourceAttr("data.k3d_nodes.test", "nodes.k3d-test-agent-0.role", "agent"),
-					resource.TestCheckResourceAttr("data.k3d_nodes.test", "nodes.k3d-test-serverlb.role", "loadbalancer"),
-					resource.TestCheckResourceAttrSet("data.k3d_nodes.test", "nodes.k3d-test-serverlb.ports.#"),
+					resource.TestCheckResourceAttr("data.k3d_nodes.test", "cluster_name", name),
+					resource.TestCheckResourceAttr("data.k3d_nodes.test", "id", name),
+					resource.TestCheckResourceAttr("data.k3d_nodes.test", node+"-server-0.name", "k3d-"+name+"-server-0"),
+					resource.TestCheckResourceAttr("data.k3d_nodes.test", node+"-server-0.role", "server"),
+					resource.TestCheckResourceAttrSet("data.k3d_nodes.test", node+"-server-0.runtime_labels.%"),
+					resource.TestCheckResourceAttr("data.k3d_nodes.test", node+"-server-0.networks.0", "k3d-"+name),
+					resource.TestCheckResourceAttrSet("data.k3d_nodes.test", node+"-server-0.ip"),
+					resource.TestCheckResourceAttr("data.k3d_nodes.test", node+"-server-0.state", "running"),
+					resource.TestCheckResourceAttr("data.k3d_nodes.test", node+"-server-0.running", "true"),
+					resource.TestCheckResourceAttrSet("data.k3d_nodes.test", node+"-server-0.status"),
+					resource.TestCheckResourceAttrSet("data.k3d_nodes.test", node+"-server-0.created"),
+					resource.TestCheckResourceAttr("data.k3d_nodes.test", node+"-server-0.memory", ""),
+					resource.TestCheckResourceAttr("data.k3d_nodes.test", node+"-agent-0.role", "agent"),
+					resource.TestCheckResourceAttr("data.k3d_nodes.test", node+"-serverlb.role", "loadbalancer"),
+					resource.TestCheckResourceAttrSet("data.k3d_nodes.test", node+"-serverlb.ports.#"),
+					resource.TestCheckResourceAttr("data.k3d_nodes.test", "loadbalancer_port_map.6443.tcp.0", "k3d-"+name+"-server-0"),
+					resource.TestCheckResourceAttr("data.k3d_nodes.agents", "nodes.%", "1"),
+					resource.TestCheckResourceAttr("data.k3d_nodes.agents", node+"-agent-0.role", "agent"),
+					resource.TestCheckResourceAttr("data.k3d_nodes.servers", "nodes.%", "1"),
+					resource.TestCheckResourceAttr("data.k3d_nodes.servers", "id", "*"),
+					resource.TestCheckResourceAttr("data.k3d_nodes.servers", "loadbalancer_port_map.%", "0"),
 				),
 			},
 		},
 	})
 }
 
-const testAccExampleDataSourceConfig = `
+func testAccExampleDataSourceConfig(name string, port int) string {
+	return fmt.Sprintf(`
 resource "k3d_cluster" "test" {
-	name = "test"
+	name = %[1]q
 	servers = 1
 	agents = 1
-	k8s_api_host_port = 6552
+	k8s_api_host_port = %[2]d
 }
 
 data "k3d_nodes" "test" {
   cluster_name = k3d_cluster.test.name
 }
+
+data "k3d_nodes" "agents" {
+  cluster_name = k3d_cluster.test.name
+  roles        = ["agent"]
+}
+
+data "k3d_nodes" "servers" {
+  name_regex = "^k3d-%[1]s-server-[0-9]+$"
+
+  labels = {
+    "k3d.cluster" = k3d_cluster.test.name
+  }
+}
+`, name, port)
+}
+
+const testUnitNodesDataSourceConfig = `
+resource "k3d_cluster" "test" {
+  name        = "unit-test"
+  agents      = 1
+  k3s_version = "v1.28.7+k3s1"
+}
+
+data "k3d_nodes" "test" {
+  cluster_name = k3d_cluster.test.name
+}
+
+data "k3d_nodes" "agents" {
+  cluster_name = k3d_cluster.test.name
+  roles        = ["agent"]
+}
 `
@@ -0,0 +1,119 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"github.com/k3d-io/k3d/v5/pkg/types/k3s"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ datasource.DataSource = k3sVersionsDataSource{}
+
+type k3sVersionsData struct {
+	ChannelServerURL types.String                `tfsdk:"channel_server_url"`
+	Id               types.String                `tfsdk:"id"`
+	Channels         map[string]k3sChannelResult `tfsdk:"channels"`
+}
+
+type k3sChannelResult struct {
+	Name    string `tfsdk:"name"`
+	Version string `tfsdk:"version"`
+	Image   string `tfsdk:"image"`
+}
+
+type k3sVersionsDataSource struct {
+}
+
+func NewK3sVersionsDataSource() datasource.DataSource {
+	return k3sVersionsDataSource{}
+}
+
+func (d k3sVersionsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data k3sVersionsData
+
+	diags := req.Config.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.ChannelServerURL.IsNull() {
+		data.ChannelServerURL = types.StringValue(k3s.K3sChannelServerURL)
+	}
+
+	tflog.Debug(ctx, fmt.Sprintf("fetching K3s release channels from %s", data.ChannelServerURL.ValueString()))
+	channels, err := fetchK3sChannels(ctx, data.ChannelServerURL.ValueString())
+	if err != nil {
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Failed to fetch K3s release channels", err.Error()))
+		return
+	}
+
+	results := make(map[string]k3sChannelResult)
+	for _, channel := range channels {
+		if channel.Name == "" || channel.Latest == "" {
+			continue
+		}
+
+		results[channel.Name] = k3sChannelResult{
+			Name:    channel.Name,
+			Version: channel.Latest,
+			Image:   k3sVersionToImage(channel.Latest),
+		}
+	}
+
+	data.Id = data.ChannelServerURL
+	data.Channels = results
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (t k3sVersionsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_k3s_versions"
+}
+
+func (t k3sVersionsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "K3s Release Channel Data Source",
+		Attributes: map[string]schema.Attribute{
+			"channel_server_url": schema.StringAttribute{
+				MarkdownDescription: fmt.Sprintf("URL of the K3s channel server to query. Defaults to `%s`", k3s.K3sChannelServerURL),
+				Optional:            true,
+				Computed:            true,
+			},
+			"id": schema.StringAttribute{
+				MarkdownDescription: "The channel server URL that was queried",
+				Computed:            true,
+			},
+			"channels": schema.MapNestedAttribute{
+				MarkdownDescription: "Map of channel names (e.g. `stable`, `latest`, `v1.29`) to the release they currently resolve to",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							MarkdownDescription: "The name of the release channel",
+							Computed:            true,
+						},
+						"version": schema.StringAttribute{
+							MarkdownDescription: "The K3s version the channel resolves to (e.g. `v1.29.2+k3s1`)",
+							Computed:            true,
+						},
+						"image": schema.StringAttribute{
+							MarkdownDescription: "The K3s node image for the resolved version, suitable for `k3d_cluster.image`",
+							Computed:            true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,61 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+const testK3sChannelServerResponse = `{
+  "type": "collection",
+  "resourceType": "channels",
+  "data": [
+    {"id": "stable", "type": "channel", "name": "stable", "latest": "v1.28.7+k3s1"},
+    {"id": "latest", "type": "channel", "name": "latest", "latest": "v1.29.2+k3s1"},
+    {"id": "v1.29", "type": "channel", "name": "v1.29", "latest": "v1.29.2+k3s1", "latestRegexp": "v1\\.29\\..*"}
+  ]
+}`
+
+func newTestK3sChannelServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, testK3sChannelServerResponse)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestAccK3sVersionsDataSource(t *testing.T) {
+	srv := newTestK3sChannelServer(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccK3sVersionsDataSourceConfig(srv.URL),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.k3d_k3s_versions.test", "id", srv.URL),
+					resource.TestCheckResourceAttr("data.k3d_k3s_versions.test", "channels.%", "3"),
+					resource.TestCheckResourceAttr("data.k3d_k3s_versions.test", "channels.stable.version", "v1.28.7+k3s1"),
+					resource.TestCheckResourceAttr("data.k3d_k3s_versions.test", "channels.stable.image", "docker.io/rancher/k3s:v1.28.7-k3s1"),
+					resource.TestCheckResourceAttr("data.k3d_k3s_versions.test", "channels.latest.version", "v1.29.2+k3s1"),
+				),
+			},
+		},
+	})
+}
+
+func testAccK3sVersionsDataSourceConfig(url string) string {
+	return fmt.Sprintf(`
+data "k3d_k3s_versions" "test" {
+  channel_server_url = %[1]q
+}
+`, url)
+}
@@ -0,0 +1,170 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	k3dtypes "github.com/k3d-io/k3d/v5/pkg/types"
+)
+
+// nodeLogLines is the number of log lines of a node that are included in
+// diagnostics about the node.
+const nodeLogLines = 30
+
+// clusterErrorRule maps k3d errors to the cluster attribute that caused them.
+type clusterErrorRule struct {
+	// fragments of the error message, matched case-insensitively
+	fragments []string
+	path      func(data *k3dClusterData) path.Path
+	hint      string
+}
+
+func (r clusterErrorRule) matches(message string) bool {
+	for _, fragment := range r.fragments {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func attributePath(name string) func(*k3dClusterData) path.Path {
+	return func(*k3dClusterData) path.Path {
+		return path.Root(name)
+	}
+}
+
+// clusterErrorRules are checked in order, so more specific rules come first.
+var clusterErrorRules = []clusterErrorRule{
+	{
+		fragments: []string{"port is already allocated", "address already in use"},
+		path:      attributePath("k8s_api_host_port"),
+		hint:      "The port of the Kubernetes API is already in use on the host. Pick another port or set k8s_api_host_port to 0 to bind a free port, which is reported in k8s_api_bound_port.",
+	},
+	{
+		fragments: []string{"pull access denied", "manifest unknown", "failed to pull image", "no such image", "repository does not exist"},
+		path: func(data *k3dClusterData) path.Path {
+			if !data.K3sVersion.IsNull() && !data.K3sVersion.IsUnknown() {
+				return path.Root("k3s_version")
+			}
+			return path.Root("image")
+		},
+		hint: "The node image could not be pulled. Check that the image and tag exist and that the registry is reachable.",
+	},
+	{
+		fragments: []string{"pool overlaps", "non-overlapping", "invalid pool request"},
+		path: func(data *k3dClusterData) path.Path {
+			if !data.Subnet.IsNull() && !data.Subnet.IsUnknown() {
+				return path.Root("subnet")
+			}
+			return path.Root("network")
+		},
+		hint: "The subnet of the cluster network overlaps with an existing network. Pick another subnet or leave it unset to let the runtime pick a free one.",
+	},
+	{
+		fragments: []string{"network with name"},
+		path:      attributePath("network"),
+		hint:      "A network with the same name already exists. Set network to its name to attach the cluster to it.",
+	},
+	{
+		fragments: []string{"hostalias"},
+		path:      attributePath("host_aliases"),
+		hint:      "The host aliases must map valid IP addresses to RFC 1123 hostnames.",
+	},
+	{
+		fragments: []string{"cluster name", "is already in use by container"},
+		path:      attributePath("name"),
+		hint:      "The cluster name must be a valid RFC 1123 hostname that is not used by another cluster.",
+	},
+}
+
+// clusterErrorDiagnostic maps an error returned by k3d for a cluster to a
+// diagnostic on the attribute responsible for it. Errors that cannot be
+// attributed are reported without an attribute path. Any details, such as
+// node logs, are appended to the error message.
+func clusterErrorDiagnostic(summary string, err error, data *k3dClusterData, details ...string) diag.Diagnostic {
+	detail := strings.Join(append([]string{err.Error()}, details...), "\n\n")
+
+	message := strings.ToLower(err.Error())
+	for _, rule := range clusterErrorRules {
+		if rule.matches(message) {
+			return diag.NewAttributeErrorDiagnostic(rule.path(data), summary, fmt.Sprintf("%s\n\n%s", rule.hint, detail))
+		}
+	}
+
+	return diag.NewErrorDiagnostic(summary, detail)
+}
+
+// failedNodeRegex matches the node names in the errors returned by k3d when
+// a node fails to be created or started.
+var failedNodeRegex = regexp.MustCompile(`(?i)node '([^']+)'|node (\S+) failed to get ready`)
+
+// failedNodeName returns the name of the node that caused the error, if any.
+func failedNodeName(err error) string {
+	match := failedNodeRegex.FindStringSubmatch(err.Error())
+	if match == nil {
+		return ""
+	}
+
+	if match[1] != "" {
+		return match[1]
+	}
+
+	return match[2]
+}
+
+// failedNodeLogs returns the last log lines of the node that caused the
+// error, formatted for a diagnostic, or nothing if they are not available.
+func failedNodeLogs(ctx context.Context, rt k3dRuntime, err error) []string {
+	name := failedNodeName(err)
+	if name == "" {
+		return nil
+	}
+
+	if logs := nodeLogsDetail(ctx, rt, name); logs != "" {
+		return []string{logs}
+	}
+
+	return nil
+}
+
+// clusterNodeLogs returns the last log lines of each node of the cluster,
+// including nodes that are not running, formatted for a diagnostic.
+func clusterNodeLogs(ctx context.Context, rt k3dRuntime, cluster string) []string {
+	nodes, err := rt.NodesByLabel(ctx, map[string]string{k3dtypes.LabelClusterName: cluster})
+	if err != nil {
+		tflog.Warn(ctx, fmt.Sprintf("failed to list the nodes of cluster %s: %v", cluster, err))
+		return nil
+	}
+
+	sortNodesByName(nodes)
+
+	var details []string
+	for _, node := range nodes {
+		if logs := nodeLogsDetail(ctx, rt, node.Name); logs != "" {
+			details = append(details, logs)
+		}
+	}
+
+	return details
+}
+
+func nodeLogsDetail(ctx context.Context, rt k3dRuntime, name string) string {
+	logs, err := rt.NodeLogs(ctx, name, nodeLogLines)
+	if err != nil {
+		tflog.Warn(ctx, err.Error(), map[string]interface{}{"node": name})
+		return ""
+	}
+
+	if logs == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("Last %d log lines of node %s:\n%s", nodeLogLines, name, strings.TrimRight(logs, "\n"))
+}
@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestClusterErrorDiagnostic(t *testing.T) {
+	cases := map[string]struct {
+		err      string
+		modify   func(data *k3dClusterData)
+		expected path.Path
+	}{
+		"port in use": {
+			err:      "failed to start node 'k3d-test-serverlb': Error response from daemon: driver failed programming external connectivity on endpoint k3d-test-serverlb: Bind for 127.0.0.1:6550 failed: port is already allocated",
+			expected: path.Root("k8s_api_host_port"),
+		},
+		"image pull": {
+			err:      "failed to pull image 'rancher/k3s:v0.0.0-k3s1': Error response from daemon: manifest unknown",
+			expected: path.Root("image"),
+		},
+		"image pull from k3s version": {
+			err: "failed to pull image 'rancher/k3s:v0.0.0-k3s1': Error response from daemon: manifest unknown",
+			modify: func(data *k3dClusterData) {
+				data.K3sVersion = types.StringValue("v0.0.0+k3s1")
+			},
+			expected: path.Root("k3s_version"),
+		},
+		"overlapping subnet": {
+			err: "failed to create cluster network: Error response from daemon: Pool overlaps with other one on this address space",
+			modify: func(data *k3dClusterData) {
+				data.Subnet = types.StringValue("172.28.0.0/16")
+			},
+			expected: path.Root("subnet"),
+		},
+		"existing network": {
+			err:      "Error response from daemon: network with name k3d-test already exists",
+			expected: path.Root("network"),
+		},
+		"host alias": {
+			err:      "invalid IP 'foo' in hostAlias 'foo: [bar]': ParseAddr(\"foo\"): unable to parse IP",
+			expected: path.Root("host_aliases"),
+		},
+		"cluster name": {
+			err:      "provided cluster name 'not_a_hostname' does not match requirements",
+			expected: path.Root("name"),
+		},
+		"unknown": {
+			err: "something went wrong",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			data := testClusterData("test")
+			if tc.modify != nil {
+				tc.modify(&data)
+			}
+
+			d := clusterErrorDiagnostic("Error creating cluster", errors.New(tc.err), &data, "node logs")
+
+			if d.Severity() != diag.SeverityError {
+				t.Errorf("expected an error, got %s", d.Severity())
+			}
+
+			if !strings.Contains(d.Detail(), tc.err) || !strings.HasSuffix(d.Detail(), "node logs") {
+				t.Errorf("expected the detail to contain the error and the details, got %q", d.Detail())
+			}
+
+			withPath, ok := d.(diag.DiagnosticWithPath)
+			if len(tc.expected.Steps()) == 0 {
+				if ok {
+					t.Errorf("expected no attribute path, got %s", withPath.Path())
+				}
+				return
+			}
+
+			if !ok || !withPath.Path().Equal(tc.expected) {
+				t.Errorf("expected an error at %s, got %v", tc.expected, d)
+			}
+		})
+	}
+}
+
+func TestFailedNodeName(t *testing.T) {
+	cases := map[string]string{
+		"failed to start node 'k3d-test-server-0': context deadline exceeded":   "k3d-test-server-0",
+		"Node k3d-test-agent-1 failed to get ready: error waiting for log line": "k3d-test-agent-1",
+		"Failed Cluster Start: failed to create cluster network":                "",
+	}
+
+	for message, expected := range cases {
+		if actual := failedNodeName(errors.New(message)); actual != expected {
+			t.Errorf("%q: expected node %q, got %q", message, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	dockerruntime "github.com/k3d-io/k3d/v5/pkg/runtimes/docker"
+	k3dtypes "github.com/k3d-io/k3d/v5/pkg/types"
+)
+
+// execResult holds the output of a command run within a node.
+type execResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// execInNode runs the command within the node and waits for it to finish.
+// The k3d runtime interface merges the output streams of the command and
+// only reports the exit code as part of an error message, so the command is
+// run through the docker API instead. A non-zero exit code is not an error.
+func execInNode(ctx context.Context, node *k3dtypes.Node, cmd []string) (*execResult, error) {
+	docker, err := dockerruntime.GetDockerClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	defer docker.Close()
+
+	exec, err := docker.ContainerExecCreate(ctx, node.Name, dockertypes.ExecConfig{
+		Privileged:   true,
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          cmd,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exec process in node %s: %w", node.Name, err)
+	}
+
+	resp, err := docker.ContainerExecAttach(ctx, exec.ID, dockertypes.ExecStartCheck{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach to exec process in node %s: %w", node.Name, err)
+	}
+	defer resp.Close()
+
+	var stdout, stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, resp.Reader); err != nil {
+		return nil, fmt.Errorf("failed to read output of exec process in node %s: %w", node.Name, err)
+	}
+
+	// the output stream may be closed slightly before the process is reported
+	// as finished
+	var inspect dockertypes.ContainerExecInspect
+	for {
+		inspect, err = docker.ContainerExecInspect(ctx, exec.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect exec process in node %s: %w", node.Name, err)
+		}
+
+		if !inspect.Running {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	return &execResult{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		ExitCode: inspect.ExitCode,
+	}, nil
+}
@@ -0,0 +1,178 @@
+package provider
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/k3d-io/k3d/v5/pkg/actions"
+	"github.com/k3d-io/k3d/v5/pkg/client"
+	config "github.com/k3d-io/k3d/v5/pkg/config/v1alpha5"
+	"github.com/k3d-io/k3d/v5/pkg/runtimes"
+	runtimeerrors "github.com/k3d-io/k3d/v5/pkg/runtimes/errors"
+	k3dtypes "github.com/k3d-io/k3d/v5/pkg/types"
+	k3dutil "github.com/k3d-io/k3d/v5/pkg/util"
+)
+
+// defaultFileNodeFilters selects the K3s nodes of the cluster.
+var defaultFileNodeFilters = []string{"server:*", "agent:*"}
+
+type k3dFileData struct {
+	Content     types.String `tfsdk:"content"`
+	Source      types.String `tfsdk:"source"`
+	Destination types.String `tfsdk:"destination"`
+	NodeFilters types.List   `tfsdk:"node_filters"`
+	ContentHash types.String `tfsdk:"content_hash"`
+}
+
+// fileContent returns the content of a file either given inline or read from
+// the source path.
+func fileContent(content, source types.String) ([]byte, error) {
+	if !content.IsNull() {
+		return []byte(content.ValueString()), nil
+	}
+
+	if source.IsNull() {
+		return nil, fmt.Errorf("either content or source must be set")
+	}
+
+	return os.ReadFile(source.ValueString())
+}
+
+// hashContent returns the hex encoded SHA-256 hash of the content.
+func hashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// nodeFileAction writes a file into the node it is run for, but only if the
+// node was selected by the file's node filters. This allows the action to be
+// registered as a cluster wide hook, which is the only kind of hook k3d runs
+// for agent nodes.
+type nodeFileAction struct {
+	actions.WriteFileAction
+	nodes map[string]struct{}
+}
+
+func (act nodeFileAction) Run(ctx context.Context, node *k3dtypes.Node) error {
+	if _, ok := act.nodes[node.Name]; !ok {
+		return nil
+	}
+
+	return act.WriteFileAction.Run(ctx, node)
+}
+
+// injectFile registers pre-start hooks that write the content into the nodes
+// of the cluster selected by the node filters.
+func injectFile(clusterConfig *config.ClusterConfig, content []byte, destination string, nodeFilters []string) error {
+	if len(nodeFilters) == 0 {
+		nodeFilters = defaultFileNodeFilters
+	}
+
+	nodes, err := k3dutil.FilterNodes(clusterConfig.Cluster.Nodes, nodeFilters)
+	if err != nil {
+		return fmt.Errorf("failed to filter nodes for file %s: %w", destination, err)
+	}
+
+	action := nodeFileAction{
+		WriteFileAction: actions.WriteFileAction{
+			Runtime:     runtimes.SelectedRuntime,
+			Content:     content,
+			Dest:        destination,
+			Mode:        0644,
+			Description: fmt.Sprintf("Write file %s", destination),
+		},
+		nodes: make(map[string]struct{}),
+	}
+
+	for _, node := range nodes {
+		action.nodes[node.Name] = struct{}{}
+
+		// helper nodes such as the load balancer only run their own hooks
+		if node.Role != k3dtypes.ServerRole && node.Role != k3dtypes.AgentRole {
+			node.HookActions = append(node.HookActions, k3dtypes.NodeHook{
+				Stage:  k3dtypes.LifecycleStagePreStart,
+				Action: action,
+			})
+		}
+	}
+
+	clusterConfig.ClusterCreateOpts.NodeHooks = append(clusterConfig.ClusterCreateOpts.NodeHooks, k3dtypes.NodeHook{
+		Stage:  k3dtypes.LifecycleStagePreStart,
+		Action: action,
+	})
+
+	return nil
+}
+
+// clusterServers returns the server nodes of the named cluster sorted by name.
+func clusterServers(ctx context.Context, clusterName string) ([]*k3dtypes.Node, error) {
+	cluster, err := client.ClusterGet(ctx, runtimes.SelectedRuntime, &k3dtypes.Cluster{Name: clusterName})
+	if err != nil {
+		return nil, err
+	}
+
+	servers := client.NodeFilterByRoles(cluster.Nodes, []k3dtypes.Role{k3dtypes.ServerRole}, nil)
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("cluster %q has no server nodes", clusterName)
+	}
+	sortNodesByName(servers)
+
+	return servers, nil
+}
+
+// writeFileToNodes writes the content into each of the running nodes.
+func writeFileToNodes(ctx context.Context, nodes []*k3dtypes.Node, content []byte, destination string) error {
+	for _, node := range nodes {
+		if err := runtimes.SelectedRuntime.WriteToNode(ctx, content, destination, 0644, node); err != nil {
+			return fmt.Errorf("failed to write %s to node %s: %w", destination, node.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// removeFileFromNodes removes the file from each of the running nodes. Nodes
+// that do not have the file are skipped.
+func removeFileFromNodes(ctx context.Context, nodes []*k3dtypes.Node, destination string) error {
+	for _, node := range nodes {
+		if err := runtimes.SelectedRuntime.ExecInNode(ctx, node, []string{"rm", "-f", destination}); err != nil {
+			return fmt.Errorf("failed to remove %s from node %s: %w", destination, node.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// readFileFromNode returns the content of a regular file within the node. The
+// returned error wraps runtimeerrors.ErrRuntimeFileNotFound if the file does
+// not exist.
+func readFileFromNode(ctx context.Context, node *k3dtypes.Node, path string) ([]byte, error) {
+	reader, err := runtimes.SelectedRuntime.ReadFromNode(ctx, path, node)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	// the runtime returns the file as a tar archive
+	archive := tar.NewReader(reader)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: %s", runtimeerrors.ErrRuntimeFileNotFound, path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from node %s: %w", path, node.Name, err)
+		}
+
+		if header.Typeflag == tar.TypeReg {
+			return io.ReadAll(archive)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/k3d-io/k3d/v5/pkg/runtimes"
+	k3dtypes "github.com/k3d-io/k3d/v5/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// helmChartNamespace is the namespace watched by the K3s helm-controller
+	// for HelmChart resources.
+	helmChartNamespace = "kube-system"
+
+	helmChartStatusPending  = "pending"
+	helmChartStatusDeployed = "deployed"
+	helmChartStatusFailed   = "failed"
+)
+
+// helmChart is the subset of the helm.cattle.io/v1 HelmChart resource that is
+// managed by the provider.
+type helmChart struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Metadata   helmChartMetadata `json:"metadata"`
+	Spec       helmChartSpec     `json:"spec"`
+}
+
+type helmChartMetadata struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+type helmChartSpec struct {
+	Chart           string `json:"chart"`
+	Repo            string `json:"repo,omitempty"`
+	Version         string `json:"version,omitempty"`
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+	CreateNamespace bool   `json:"createNamespace,omitempty"`
+	ValuesContent   string `json:"valuesContent,omitempty"`
+}
+
+// renderHelmChart renders the HelmChart manifest for the given spec.
+func renderHelmChart(name string, spec helmChartSpec) ([]byte, error) {
+	if spec.ValuesContent != "" {
+		var values map[string]interface{}
+		if err := yaml.Unmarshal([]byte(spec.ValuesContent), &values); err != nil {
+			return nil, fmt.Errorf("invalid chart values: %w", err)
+		}
+	}
+
+	return yaml.Marshal(helmChart{
+		APIVersion: "helm.cattle.io/v1",
+		Kind:       "HelmChart",
+		Metadata: helmChartMetadata{
+			Name:      name,
+			Namespace: helmChartNamespace,
+		},
+		Spec: spec,
+	})
+}
+
+// parseHelmChart parses a HelmChart manifest previously rendered by
+// renderHelmChart.
+func parseHelmChart(content []byte) (helmChart, error) {
+	var chart helmChart
+	if err := yaml.Unmarshal(content, &chart); err != nil {
+		return chart, fmt.Errorf("failed to parse HelmChart manifest: %w", err)
+	}
+
+	if chart.Kind != "HelmChart" {
+		return chart, fmt.Errorf("manifest is a %q rather than a HelmChart", chart.Kind)
+	}
+
+	return chart, nil
+}
+
+// helmChartStatus reports the install status of a HelmChart by inspecting the
+// install job that helm-controller runs for it. Charts whose job has not been
+// created or has not finished yet are pending.
+func helmChartStatus(ctx context.Context, server *k3dtypes.Node, name string) string {
+	cmd := []string{
+		"kubectl", "get", "job",
+		"--namespace", helmChartNamespace,
+		fmt.Sprintf("helm-install-%s", name),
+		"--output", `jsonpath={.status.conditions[?(@.status=="True")].type}`,
+	}
+
+	logs, err := runtimes.SelectedRuntime.ExecInNodeGetLogs(ctx, server, cmd)
+	if err != nil || logs == nil {
+		return helmChartStatusPending
+	}
+
+	var buf strings.Builder
+	_, _ = logs.WriteTo(&buf)
+
+	return helmChartStatusFromConditions(buf.String())
+}
+
+// helmChartStatusFromConditions maps the true conditions of an install job to
+// the status of the chart.
+func helmChartStatusFromConditions(conditions string) string {
+	for _, condition := range strings.Fields(conditions) {
+		switch condition {
+		case "Complete":
+			return helmChartStatusDeployed
+		case "Failed":
+			return helmChartStatusFailed
+		}
+	}
+
+	return helmChartStatusPending
+}
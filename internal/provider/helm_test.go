@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRenderHelmChart(t *testing.T) {
+	spec := helmChartSpec{
+		Chart:           "grafana",
+		Repo:            "https://grafana.github.io/helm-charts",
+		Version:         "7.3.0",
+		TargetNamespace: "monitoring",
+		CreateNamespace: true,
+		ValuesContent:   "replicas: 2\n",
+	}
+
+	content, err := renderHelmChart("grafana", spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `apiVersion: helm.cattle.io/v1
+kind: HelmChart
+metadata:
+  name: grafana
+  namespace: kube-system
+spec:
+  chart: grafana
+  createNamespace: true
+  repo: https://grafana.github.io/helm-charts
+  targetNamespace: monitoring
+  valuesContent: |
+    replicas: 2
+  version: 7.3.0
+`
+	if string(content) != expected {
+		t.Errorf("unexpected manifest:\n%s\nexpected:\n%s", content, expected)
+	}
+
+	chart, err := parseHelmChart(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if chart.Metadata.Name != "grafana" {
+		t.Errorf("parsed name %q, expected %q", chart.Metadata.Name, "grafana")
+	}
+
+	if !reflect.DeepEqual(chart.Spec, spec) {
+		t.Errorf("parsed spec %+v, expected %+v", chart.Spec, spec)
+	}
+}
+
+func TestRenderHelmChartInvalidValues(t *testing.T) {
+	_, err := renderHelmChart("grafana", helmChartSpec{Chart: "grafana", ValuesContent: "- not\n- a map"})
+	if err == nil {
+		t.Fatal("expected an error for values that are not a map")
+	}
+}
+
+func TestParseHelmChartWrongKind(t *testing.T) {
+	_, err := parseHelmChart([]byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: foo\n"))
+	if err == nil {
+		t.Fatal("expected an error for a manifest that is not a HelmChart")
+	}
+}
+
+func TestHelmChartStatusFromConditions(t *testing.T) {
+	cases := map[string]string{
+		"":                                helmChartStatusPending,
+		"Complete":                        helmChartStatusDeployed,
+		"Failed":                          helmChartStatusFailed,
+		"SuccessCriteriaMet Complete\r\n": helmChartStatusDeployed,
+	}
+
+	for conditions, expected := range cases {
+		if actual := helmChartStatusFromConditions(conditions); actual != expected {
+			t.Errorf("helmChartStatusFromConditions(%q) = %q, expected %q", conditions, actual, expected)
+		}
+	}
+}
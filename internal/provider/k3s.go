@@ -0,0 +1,115 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	k3dtypes "github.com/k3d-io/k3d/v5/pkg/types"
+	"github.com/k3d-io/k3d/v5/pkg/types/k3s"
+	"github.com/k3d-io/k3d/v5/version"
+)
+
+// k3sVersionRegex matches K3s release versions such as v1.29.2+k3s1 or
+// v1.30.0-rc1+k3s1.
+var k3sVersionRegex = regexp.MustCompile(`^v[0-9]+\.[0-9]+\.[0-9]+(-[0-9A-Za-z.]+)?\+k3s[0-9]+$`)
+
+// k3sVersionToImage converts a K3s release version into the reference of the
+// rancher/k3s image for that release. Image tags cannot contain a `+` so the
+// K3s build metadata separator is replaced with a `-`.
+func k3sVersionToImage(version string) string {
+	return fmt.Sprintf("%s:%s", k3dtypes.DefaultK3sImageRepo, strings.ReplaceAll(version, "+", "-"))
+}
+
+// imageToK3sVersion extracts the K3s release version from a K3s image
+// reference. The second return value is false if the image tag does not
+// look like a K3s release.
+func imageToK3sVersion(image string) (string, bool) {
+	// strip any digest before looking for the tag
+	if idx := strings.Index(image, "@"); idx != -1 {
+		image = image[:idx]
+	}
+
+	idx := strings.LastIndex(image, ":")
+	if idx == -1 || strings.Contains(image[idx:], "/") {
+		return "", false
+	}
+	tag := image[idx+1:]
+
+	sep := strings.LastIndex(tag, "-")
+	if sep == -1 {
+		return "", false
+	}
+
+	version := tag[:sep] + "+" + tag[sep+1:]
+	if !k3sVersionRegex.MatchString(version) {
+		return "", false
+	}
+
+	return version, true
+}
+
+// fetchK3sChannels retrieves the list of release channels from a K3s channel
+// server such as https://update.k3s.io/v1-release/channels.
+func fetchK3sChannels(ctx context.Context, url string) ([]k3s.Channel, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request to %q failed with status code %d", url, resp.StatusCode)
+	}
+
+	var out k3s.ChannelServerResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("error decoding channel server response: %w", err)
+	}
+
+	channels := make([]k3s.Channel, 0, len(out.Channels))
+	for _, channel := range out.Channels {
+		channels = append(channels, channel.Channel)
+	}
+
+	return channels, nil
+}
+
+// isK3sChannelImage reports whether the image is one of the special values
+// understood by k3d (`latest`, `stable` and `+<channel>`) that are resolved
+// against the K3s channel server.
+func isK3sChannelImage(image string) bool {
+	return image == "latest" || image == "stable" || strings.HasPrefix(image, "+")
+}
+
+// k3sDefaultImage returns the image of the K3s version that k3d was built
+// with. It is used in place of release channels where they cannot be
+// resolved.
+func k3sDefaultImage() string {
+	return fmt.Sprintf("%s:%s", k3dtypes.DefaultK3sImageRepo, version.K3sVersion)
+}
+
+// resolveK3sImage resolves the special image values understood by k3d into a
+// concrete K3s image the same way that k3d does when creating a cluster.
+// Other images are returned unchanged.
+func resolveK3sImage(image string) (string, error) {
+	if !isK3sChannelImage(image) {
+		return image, nil
+	}
+
+	v, err := version.GetK3sVersion(strings.TrimPrefix(image, "+"))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s:%s", k3dtypes.DefaultK3sImageRepo, v), nil
+}
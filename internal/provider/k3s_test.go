@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"context"
+	"testing"
+)
+
+func TestK3sVersionToImage(t *testing.T) {
+	cases := map[string]string{
+		"v1.29.2+k3s1":     "docker.io/rancher/k3s:v1.29.2-k3s1",
+		"v1.30.0-rc1+k3s1": "docker.io/rancher/k3s:v1.30.0-rc1-k3s1",
+	}
+
+	for version, expected := range cases {
+		if actual := k3sVersionToImage(version); actual != expected {
+			t.Errorf("k3sVersionToImage(%q) = %q, expected %q", version, actual, expected)
+		}
+	}
+}
+
+func TestImageToK3sVersion(t *testing.T) {
+	cases := map[string]struct {
+		version string
+		ok      bool
+	}{
+		"docker.io/rancher/k3s:v1.29.2-k3s1":                {"v1.29.2+k3s1", true},
+		"rancher/k3s:v1.30.0-rc1-k3s1":                      {"v1.30.0-rc1+k3s1", true},
+		"localhost:5000/rancher/k3s:v1.28.7-k3s1@sha256:ab": {"v1.28.7+k3s1", true},
+		"localhost:5000/rancher/k3s":                        {"", false},
+		"rancher/k3s:latest":                                {"", false},
+		"rancher/k3s":                                       {"", false},
+		"":                                                  {"", false},
+	}
+
+	for image, expected := range cases {
+		version, ok := imageToK3sVersion(image)
+		if version != expected.version || ok != expected.ok {
+			t.Errorf("imageToK3sVersion(%q) = (%q, %t), expected (%q, %t)", image, version, ok, expected.version, expected.ok)
+		}
+	}
+}
+
+func TestFetchK3sChannels(t *testing.T) {
+	srv := newTestK3sChannelServer(t)
+
+	channels, err := fetchK3sChannels(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	latest := make(map[string]string)
+	for _, channel := range channels {
+		latest[channel.Name] = channel.Latest
+	}
+
+	if len(latest) != 3 {
+		t.Fatalf("expected 3 channels, got %d", len(latest))
+	}
+	if latest["stable"] != "v1.28.7+k3s1" {
+		t.Errorf("unexpected stable version %q", latest["stable"])
+	}
+	if latest["v1.29"] != "v1.29.2+k3s1" {
+		t.Errorf("unexpected v1.29 version %q", latest["v1.29"])
+	}
+}
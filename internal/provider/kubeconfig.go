@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/k3d-io/k3d/v5/pkg/client"
+	"github.com/k3d-io/k3d/v5/pkg/runtimes"
+	k3dtypes "github.com/k3d-io/k3d/v5/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// clusterKubeconfig returns the kubeconfig that k3d generates for the
+// cluster.
+func clusterKubeconfig(ctx context.Context, cluster *k3dtypes.Cluster) (*clientcmdapi.Config, error) {
+	kubeconfig, err := client.KubeconfigGet(ctx, runtimes.SelectedRuntime, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range kubeconfig.Clusters {
+		c.Server = bracketServerIPv6(c.Server)
+	}
+
+	return kubeconfig, nil
+}
+
+// bracketServerIPv6 encloses an IPv6 host in a server URL in brackets. k3d
+// formats the server URL from the API host without brackets, which produces
+// an invalid URL when the API is bound to an IPv6 address.
+func bracketServerIPv6(server string) string {
+	hostPort, ok := strings.CutPrefix(server, "https://")
+	if !ok {
+		return server
+	}
+
+	idx := strings.LastIndex(hostPort, ":")
+	if idx == -1 {
+		return server
+	}
+
+	host, port := hostPort[:idx], hostPort[idx+1:]
+	if ip := net.ParseIP(host); ip == nil || ip.To4() != nil {
+		return server
+	}
+
+	return "https://" + net.JoinHostPort(host, port)
+}
+
+// writeKubeconfig merges the kubeconfig of the cluster into the default
+// kubeconfig file, replacing any existing entries for the cluster.
+func writeKubeconfig(ctx context.Context, cluster *k3dtypes.Cluster) error {
+	kubeconfig, err := clusterKubeconfig(ctx, cluster)
+	if err != nil {
+		return fmt.Errorf("failed to get kubeconfig for cluster '%s': %w", cluster.Name, err)
+	}
+
+	output, err := client.KubeconfigGetDefaultPath()
+	if err != nil {
+		return fmt.Errorf("failed to get default kubeconfig path: %w", err)
+	}
+
+	existing, err := clientcmd.LoadFromFile(output)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+			return fmt.Errorf("failed to create output directory '%s': %w", filepath.Dir(output), err)
+		}
+		existing = clientcmdapi.NewConfig()
+	} else if err != nil {
+		return fmt.Errorf("failed to open output file '%s' or it's not a kubeconfig: %w", output, err)
+	}
+
+	return client.KubeconfigMerge(ctx, kubeconfig, existing, output, true, false)
+}
+
+// kubernetesClient creates a Kubernetes client for the cluster from the
+// kubeconfig that k3d generates for it.
+func kubernetesClient(ctx context.Context, cluster *k3dtypes.Cluster) (kubernetes.Interface, error) {
+	kubeconfig, err := clusterKubeconfig(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := clientcmd.NewDefaultClientConfig(*kubeconfig, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client configuration: %w", err)
+	}
+
+	return kubernetes.NewForConfig(config)
+}
@@ -0,0 +1,22 @@
+package provider
+
+import "testing"
+
+func TestBracketServerIPv6(t *testing.T) {
+	cases := map[string]string{
+		"https://127.0.0.1:6550":       "https://127.0.0.1:6550",
+		"https://k3d.local:6550":       "https://k3d.local:6550",
+		"https://::1:6550":             "https://[::1]:6550",
+		"https://fd00:10::1:6550":      "https://[fd00:10::1]:6550",
+		"https://[::1]:6550":           "https://[::1]:6550",
+		"https://0.0.0.0:6550":         "https://0.0.0.0:6550",
+		"http://::1:6550":              "http://::1:6550",
+		"https://host.docker.internal": "https://host.docker.internal",
+	}
+
+	for server, expected := range cases {
+		if actual := bracketServerIPv6(server); actual != expected {
+			t.Errorf("bracketServerIPv6(%q) = %q, expected %q", server, actual, expected)
+		}
+	}
+}
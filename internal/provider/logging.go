@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	l "github.com/k3d-io/k3d/v5/pkg/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// k3dSubsystem is the tflog subsystem that the output of the k3d library is
+// logged to.
+const k3dSubsystem = "k3d"
+
+var (
+	k3dLogHook     = &tflogHook{}
+	k3dLogHookOnce sync.Once
+)
+
+// bridgeK3dLogger routes the output of the global k3d logger to the k3d
+// subsystem of the provider logger in the context instead of stderr.
+func bridgeK3dLogger(ctx context.Context) {
+	k3dLogHook.setContext(newK3dLogContext(ctx))
+
+	k3dLogHookOnce.Do(func() {
+		logger := l.Log()
+		logger.SetOutput(io.Discard)
+		// the level is filtered by tflog
+		logger.SetLevel(logrus.TraceLevel)
+		logger.AddHook(k3dLogHook)
+	})
+}
+
+// withK3dLogContext routes the output of the k3d logger to the context of an
+// operation, including its fields such as the cluster name, until the
+// returned function is called. It is meant to wrap calls into k3d:
+//
+//	defer withK3dLogContext(ctx)()
+//
+// The k3d logger is global, so its entries cannot be attributed to an
+// operation while several operations call into k3d at the same time, e.g.
+// when clusters are created in parallel. Entries are then written to the
+// context of the provider configuration without the fields of any operation.
+func withK3dLogContext(ctx context.Context) func() {
+	return k3dLogHook.pushContext(ctx, newK3dLogContext(ctx))
+}
+
+func newK3dLogContext(ctx context.Context) context.Context {
+	return tflog.NewSubsystem(ctx, k3dSubsystem, tflog.WithRootFields())
+}
+
+// tflogHook is a logrus hook that writes log entries to a tflog subsystem.
+// The k3d library logs without a context, so the entries are written to the
+// context of the call into k3d in progress if all calls in progress belong to
+// the same operation, or to the context of the last provider configuration
+// otherwise.
+type tflogHook struct {
+	mu     sync.RWMutex
+	ctx    context.Context
+	active []*hookContext
+}
+
+// hookContext is a pointer to a context so that concurrent calls can remove
+// the context they pushed regardless of the order they finish in. The
+// context of the operation identifies nested calls of the same operation.
+type hookContext struct {
+	operation context.Context
+	ctx       context.Context
+}
+
+func (h *tflogHook) setContext(ctx context.Context) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.ctx = ctx
+}
+
+func (h *tflogHook) pushContext(operation, ctx context.Context) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hc := &hookContext{operation: operation, ctx: ctx}
+	h.active = append(h.active, hc)
+
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		for i, active := range h.active {
+			if active == hc {
+				h.active = append(h.active[:i:i], h.active[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (h *tflogHook) context() context.Context {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if len(h.active) == 0 {
+		return h.ctx
+	}
+
+	// entries of concurrent calls would otherwise carry the fields of
+	// whichever call started last
+	for _, active := range h.active[1:] {
+		if active.operation != h.active[0].operation {
+			return h.ctx
+		}
+	}
+
+	return h.active[len(h.active)-1].ctx
+}
+
+func (h *tflogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *tflogHook) Fire(entry *logrus.Entry) error {
+	ctx := h.context()
+	if ctx == nil {
+		return nil
+	}
+
+	fields := make(map[string]interface{}, len(entry.Data))
+	for key, value := range entry.Data {
+		// errors would otherwise be encoded as empty objects
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		fields[key] = value
+	}
+
+	message := strings.TrimSpace(entry.Message)
+
+	switch entry.Level {
+	case logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel:
+		tflog.SubsystemError(ctx, k3dSubsystem, message, fields)
+	case logrus.WarnLevel:
+		tflog.SubsystemWarn(ctx, k3dSubsystem, message, fields)
+	case logrus.InfoLevel:
+		tflog.SubsystemInfo(ctx, k3dSubsystem, message, fields)
+	case logrus.DebugLevel:
+		tflog.SubsystemDebug(ctx, k3dSubsystem, message, fields)
+	default:
+		tflog.SubsystemTrace(ctx, k3dSubsystem, message, fields)
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+	l "github.com/k3d-io/k3d/v5/pkg/logger"
+	"github.com/sirupsen/logrus"
+)
+
+func TestBridgeK3dLogger(t *testing.T) {
+	var output bytes.Buffer
+	bridgeK3dLogger(tflogtest.RootLogger(context.Background(), &output))
+
+	cases := map[logrus.Level]string{
+		logrus.ErrorLevel: "error",
+		logrus.WarnLevel:  "warn",
+		logrus.InfoLevel:  "info",
+		logrus.DebugLevel: "debug",
+		logrus.TraceLevel: "trace",
+	}
+
+	for level, expected := range cases {
+		t.Run(expected, func(t *testing.T) {
+			output.Reset()
+
+			l.Log().WithField("node", "k3d-test-server-0").WithError(errors.New("boom")).Log(level, "starting node\n")
+
+			entries, err := tflogtest.MultilineJSONDecode(&output)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(entries) != 1 {
+				t.Fatalf("expected a single log entry, got %v", entries)
+			}
+
+			entry := entries[0]
+			if entry["@level"] != expected {
+				t.Errorf("expected level %s, got %v", expected, entry["@level"])
+			}
+			if entry["@module"] != "provider.k3d" {
+				t.Errorf("expected the k3d subsystem, got %v", entry["@module"])
+			}
+			if entry["@message"] != "starting node" {
+				t.Errorf("expected the trimmed message, got %q", entry["@message"])
+			}
+			if entry["node"] != "k3d-test-server-0" || entry["error"] != "boom" {
+				t.Errorf("expected the entry fields, got %v", entry)
+			}
+		})
+	}
+}
+
+func TestWithK3dLogContext(t *testing.T) {
+	var output, operationOutput bytes.Buffer
+	bridgeK3dLogger(tflogtest.RootLogger(context.Background(), &output))
+
+	ctx := tflog.SetField(tflogtest.RootLogger(context.Background(), &operationOutput), "cluster", "test")
+	restore := withK3dLogContext(ctx)
+
+	l.Log().Info("creating cluster")
+
+	entries, err := tflogtest.MultilineJSONDecode(&operationOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("expected a single log entry in the operation context, got %v", entries)
+	}
+	if entries[0]["cluster"] != "test" {
+		t.Errorf("expected the cluster field of the operation, got %v", entries[0])
+	}
+	if output.Len() != 0 {
+		t.Errorf("expected no log entries in the provider context, got %s", output.String())
+	}
+
+	// entries are written to the provider context again once the call is done
+	restore()
+	l.Log().Info("cluster created")
+
+	entries, err = tflogtest.MultilineJSONDecode(&output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(entries) != 1 || entries[0]["cluster"] != nil {
+		t.Errorf("expected a single log entry without the cluster field, got %v", entries)
+	}
+}
+
+func TestWithK3dLogContext_concurrent(t *testing.T) {
+	var output, firstOutput, secondOutput bytes.Buffer
+	bridgeK3dLogger(tflogtest.RootLogger(context.Background(), &output))
+
+	firstCtx := tflog.SetField(tflogtest.RootLogger(context.Background(), &firstOutput), "cluster", "first")
+	defer withK3dLogContext(firstCtx)()
+	second := withK3dLogContext(tflog.SetField(tflogtest.RootLogger(context.Background(), &secondOutput), "cluster", "second"))
+
+	// the entry cannot be attributed to either call
+	l.Log().Info("creating cluster")
+
+	entries, err := tflogtest.MultilineJSONDecode(&output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(entries) != 1 || entries[0]["cluster"] != nil {
+		t.Errorf("expected a single log entry without the cluster field, got %v", entries)
+	}
+	if firstOutput.Len() != 0 || secondOutput.Len() != 0 {
+		t.Errorf("expected no log entries in the operation contexts, got %q and %q", firstOutput.String(), secondOutput.String())
+	}
+
+	// entries are attributed again once a single operation is left, also
+	// from nested calls of that operation
+	second()
+	nested := withK3dLogContext(firstCtx)
+	l.Log().Info("cluster created")
+	nested()
+
+	entries, err = tflogtest.MultilineJSONDecode(&firstOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(entries) != 1 || entries[0]["cluster"] != "first" {
+		t.Errorf("expected a single log entry with the cluster field of the remaining call, got %v", entries)
+	}
+}
@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	imageFromK3sVersion       = &k3sVersionImageModifier{}
+	unknownOnImageChange      = &imageChangeModifier{}
+	hashFileContent           = &fileContentHashModifier{}
+	requiresReplaceIfRecreate = stringplanmodifier.RequiresReplaceIf(
+		func(ctx context.Context, req planmodifier.StringRequest, resp *stringplanmodifier.RequiresReplaceIfFuncResponse) {
+			var strategy types.String
+			resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("upgrade_strategy"), &strategy)...)
+			resp.RequiresReplace = strategy.ValueString() != upgradeStrategyRolling
+		},
+		"Changing this value requires replacing the cluster unless upgrade_strategy is rolling",
+		"Changing this value requires replacing the cluster unless `upgrade_strategy` is `rolling`",
+	)
+)
+
+// k3sVersionImageModifier plans the node image from the k3s_version
+// attribute when the image was not configured explicitly.
+type k3sVersionImageModifier struct{}
+
+// Description returns a plain text description of the modifier's behavior.
+func (m *k3sVersionImageModifier) Description(context.Context) string {
+	return "Derives the node image from k3s_version when the image is not configured"
+}
+
+// MarkdownDescription returns a markdown formatted description of the modifier's behavior.
+func (m *k3sVersionImageModifier) MarkdownDescription(context.Context) string {
+	return "Derives the node image from `k3s_version` when the image is not configured"
+}
+
+// PlanModifyString performs the plan modification.
+func (m *k3sVersionImageModifier) PlanModifyString(ctx context.Context, req planmodifier.StringRequest, resp *planmodifier.StringResponse) {
+	if !req.ConfigValue.IsNull() {
+		return
+	}
+
+	var version types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("k3s_version"), &version)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if version.IsNull() {
+		return
+	}
+
+	if version.IsUnknown() {
+		resp.PlanValue = types.StringUnknown()
+		return
+	}
+
+	resp.PlanValue = types.StringValue(k3sVersionToImage(version.ValueString()))
+}
+
+// imageChangeModifier marks a computed attribute that is derived from the
+// node image as unknown when the image is being changed in place. It must be
+// ordered after UseStateForUnknown so that the prior state is not reused.
+type imageChangeModifier struct{}
+
+// Description returns a plain text description of the modifier's behavior.
+func (m *imageChangeModifier) Description(context.Context) string {
+	return "The value will be recomputed when the node image changes"
+}
+
+// MarkdownDescription returns a markdown formatted description of the modifier's behavior.
+func (m *imageChangeModifier) MarkdownDescription(context.Context) string {
+	return "The value will be recomputed when the node image changes"
+}
+
+// PlanModifyString performs the plan modification.
+func (m *imageChangeModifier) PlanModifyString(ctx context.Context, req planmodifier.StringRequest, resp *planmodifier.StringResponse) {
+	if req.State.Raw.IsNull() || !req.ConfigValue.IsNull() {
+		return
+	}
+
+	var configImage, planImage, stateImage, version types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("image"), &configImage)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("k3s_version"), &version)...)
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("image"), &planImage)...)
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("image"), &stateImage)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Plan modifications of other attributes are not visible here so the
+	// image derived from k3s_version has to be computed again.
+	if configImage.IsNull() && !version.IsNull() {
+		planImage = types.StringUnknown()
+		if !version.IsUnknown() {
+			planImage = types.StringValue(k3sVersionToImage(version.ValueString()))
+		}
+	}
+
+	if !planImage.Equal(stateImage) {
+		resp.PlanValue = types.StringUnknown()
+	}
+}
+
+// fileContentHashModifier plans the hash of a file's content from the sibling
+// content or source attributes, so that changes to the contents of a source
+// file are detected even though the configuration did not change.
+type fileContentHashModifier struct{}
+
+// Description returns a plain text description of the modifier's behavior.
+func (m *fileContentHashModifier) Description(context.Context) string {
+	return "Computes the SHA-256 hash of the file content"
+}
+
+// MarkdownDescription returns a markdown formatted description of the modifier's behavior.
+func (m *fileContentHashModifier) MarkdownDescription(context.Context) string {
+	return "Computes the SHA-256 hash of the file content"
+}
+
+// PlanModifyString performs the plan modification.
+func (m *fileContentHashModifier) PlanModifyString(ctx context.Context, req planmodifier.StringRequest, resp *planmodifier.StringResponse) {
+	var content, source types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, req.Path.ParentPath().AtName("content"), &content)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, req.Path.ParentPath().AtName("source"), &source)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if content.IsUnknown() || source.IsUnknown() {
+		resp.PlanValue = types.StringUnknown()
+		return
+	}
+
+	data, err := fileContent(content, source)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path.ParentPath(), "Error reading file content", err.Error())
+		return
+	}
+
+	resp.PlanValue = types.StringValue(hashContent(data))
+}
@@ -0,0 +1,119 @@
+package provider
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/docker/go-connections/nat"
+	k3dtypes "github.com/k3d-io/k3d/v5/pkg/types"
+)
+
+// portConflict is a node of another cluster that binds a host port.
+type portConflict struct {
+	Node    string
+	Cluster string
+}
+
+// findPortConflicts returns the nodes of clusters other than the given one
+// that bind the same host port. Both the port bindings of the nodes and the
+// API labels of the server nodes are considered, as the latter are set even
+// when the API is exposed through the load balancer.
+func findPortConflicts(nodes []*k3dtypes.Node, cluster string, binding nat.PortBinding) []portConflict {
+	var conflicts []portConflict
+	for _, node := range nodes {
+		owner := node.RuntimeLabels[k3dtypes.LabelClusterName]
+		if owner == cluster {
+			continue
+		}
+
+		bindings := nodeHostBindings(node)
+		for _, bound := range bindings {
+			if bindingsOverlap(bound, binding) {
+				conflicts = append(conflicts, portConflict{Node: node.Name, Cluster: owner})
+				break
+			}
+		}
+	}
+
+	return conflicts
+}
+
+func nodeHostBindings(node *k3dtypes.Node) []nat.PortBinding {
+	var bindings []nat.PortBinding
+	for _, portBindings := range node.Ports {
+		bindings = append(bindings, portBindings...)
+	}
+
+	if node.Role == k3dtypes.ServerRole {
+		if port, ok := node.RuntimeLabels[k3dtypes.LabelServerAPIPort]; ok {
+			bindings = append(bindings, nat.PortBinding{
+				HostIP:   node.RuntimeLabels[k3dtypes.LabelServerAPIHostIP],
+				HostPort: port,
+			})
+		}
+	}
+
+	return bindings
+}
+
+// bindingsOverlap reports whether two host port bindings cannot be bound at
+// the same time, i.e. they use the same port and either the same address or
+// one of them binds all addresses.
+func bindingsOverlap(a, b nat.PortBinding) bool {
+	if a.HostPort == "" || a.HostPort != b.HostPort {
+		return false
+	}
+
+	addrA, unspecifiedA := parseBindingIP(a.HostIP)
+	addrB, unspecifiedB := parseBindingIP(b.HostIP)
+	if unspecifiedA || unspecifiedB {
+		return true
+	}
+
+	return addrA == addrB
+}
+
+// parseBindingIP parses the host IP of a port binding and reports whether it
+// binds all addresses.
+func parseBindingIP(ip string) (netip.Addr, bool) {
+	ip = unbracketIP(ip)
+	if ip == "" {
+		return netip.Addr{}, true
+	}
+
+	if ip == "localhost" {
+		ip = "127.0.0.1"
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), addr.IsUnspecified()
+}
+
+// hostPortInUse reports whether the port binding is already taken on this
+// host. Failures other than the address being in use, e.g. when the address
+// is not assigned to this host, are not treated as a conflict.
+func hostPortInUse(binding nat.PortBinding) bool {
+	listener, err := net.Listen("tcp", net.JoinHostPort(unbracketIP(binding.HostIP), binding.HostPort))
+	if err != nil {
+		return errors.Is(err, syscall.EADDRINUSE)
+	}
+	listener.Close()
+
+	return false
+}
+
+// localRuntime reports whether the container runtime binds ports on this
+// host, in which case ports in use on this host conflict with the runtime.
+func localRuntime() bool {
+	host := os.Getenv("DOCKER_HOST")
+
+	return host == "" || strings.HasPrefix(host, "unix://") || strings.HasPrefix(host, "npipe://")
+}
@@ -0,0 +1,119 @@
+package provider
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/docker/go-connections/nat"
+	k3dtypes "github.com/k3d-io/k3d/v5/pkg/types"
+)
+
+func TestBindingsOverlap(t *testing.T) {
+	cases := map[string]struct {
+		a, b     nat.PortBinding
+		expected bool
+	}{
+		"same address":        {nat.PortBinding{HostIP: "127.0.0.1", HostPort: "6550"}, nat.PortBinding{HostIP: "127.0.0.1", HostPort: "6550"}, true},
+		"different port":      {nat.PortBinding{HostIP: "127.0.0.1", HostPort: "6550"}, nat.PortBinding{HostIP: "127.0.0.1", HostPort: "6551"}, false},
+		"different address":   {nat.PortBinding{HostIP: "127.0.0.1", HostPort: "6550"}, nat.PortBinding{HostIP: "127.0.0.2", HostPort: "6550"}, false},
+		"all ipv4 addresses":  {nat.PortBinding{HostIP: "0.0.0.0", HostPort: "6550"}, nat.PortBinding{HostIP: "127.0.0.1", HostPort: "6550"}, true},
+		"all ipv6 addresses":  {nat.PortBinding{HostIP: "127.0.0.1", HostPort: "6550"}, nat.PortBinding{HostIP: "::", HostPort: "6550"}, true},
+		"empty address":       {nat.PortBinding{HostPort: "6550"}, nat.PortBinding{HostIP: "127.0.0.1", HostPort: "6550"}, true},
+		"localhost":           {nat.PortBinding{HostIP: "localhost", HostPort: "6550"}, nat.PortBinding{HostIP: "127.0.0.1", HostPort: "6550"}, true},
+		"bracketed ipv6":      {nat.PortBinding{HostIP: "[::1]", HostPort: "6550"}, nat.PortBinding{HostIP: "::1", HostPort: "6550"}, true},
+		"ipv4 mapped":         {nat.PortBinding{HostIP: "::ffff:127.0.0.1", HostPort: "6550"}, nat.PortBinding{HostIP: "127.0.0.1", HostPort: "6550"}, true},
+		"random port":         {nat.PortBinding{HostIP: "127.0.0.1"}, nat.PortBinding{HostIP: "127.0.0.1"}, false},
+		"ipv4 and ipv6 local": {nat.PortBinding{HostIP: "127.0.0.1", HostPort: "6550"}, nat.PortBinding{HostIP: "::1", HostPort: "6550"}, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if actual := bindingsOverlap(tc.a, tc.b); actual != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFindPortConflicts(t *testing.T) {
+	nodes := []*k3dtypes.Node{
+		{
+			Name:          "k3d-other-serverlb",
+			Role:          k3dtypes.LoadBalancerRole,
+			RuntimeLabels: map[string]string{k3dtypes.LabelClusterName: "other"},
+			Ports: nat.PortMap{
+				"6443/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "6550"}},
+			},
+		},
+		{
+			Name: "k3d-nolb-server-0",
+			Role: k3dtypes.ServerRole,
+			RuntimeLabels: map[string]string{
+				k3dtypes.LabelClusterName:     "nolb",
+				k3dtypes.LabelServerAPIHostIP: "127.0.0.1",
+				k3dtypes.LabelServerAPIPort:   "6551",
+			},
+		},
+		{
+			Name: "k3d-test-server-0",
+			Role: k3dtypes.ServerRole,
+			RuntimeLabels: map[string]string{
+				k3dtypes.LabelClusterName:     "test",
+				k3dtypes.LabelServerAPIHostIP: "127.0.0.1",
+				k3dtypes.LabelServerAPIPort:   "6552",
+			},
+		},
+	}
+
+	cases := map[string]struct {
+		binding  nat.PortBinding
+		expected []portConflict
+	}{
+		"load balancer": {
+			binding:  nat.PortBinding{HostIP: "127.0.0.1", HostPort: "6550"},
+			expected: []portConflict{{Node: "k3d-other-serverlb", Cluster: "other"}},
+		},
+		"server label": {
+			binding:  nat.PortBinding{HostIP: "0.0.0.0", HostPort: "6551"},
+			expected: []portConflict{{Node: "k3d-nolb-server-0", Cluster: "nolb"}},
+		},
+		"same cluster": {
+			binding: nat.PortBinding{HostIP: "127.0.0.1", HostPort: "6552"},
+		},
+		"free": {
+			binding: nat.PortBinding{HostIP: "127.0.0.1", HostPort: "6553"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if actual := findPortConflicts(nodes, "test", tc.binding); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected conflicts %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestHostPortInUse(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("unable to listen on the loopback address: %v", err)
+	}
+	defer listener.Close()
+
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !hostPortInUse(nat.PortBinding{HostIP: "127.0.0.1", HostPort: port}) {
+		t.Errorf("expected port %s to be in use", port)
+	}
+
+	listener.Close()
+
+	if hostPortInUse(nat.PortBinding{HostIP: "127.0.0.1", HostPort: port}) {
+		t.Errorf("expected port %s to be free after closing the listener", port)
+	}
+}
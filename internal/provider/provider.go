@@ -21,9 +21,17 @@ type k3dProvider struct {
 	// provider is built and ran locally, and "test" when running acceptance
 	// testing.
 	version string
+
+	// runtime is passed to the resources and data sources that manage k3d
+	// clusters.
+	runtime k3dRuntime
 }
 
 func (p *k3dProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	bridgeK3dLogger(ctx)
+
+	resp.DataSourceData = p.runtime
+	resp.ResourceData = p.runtime
 }
 
 func (p *k3dProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -34,12 +42,18 @@ func (p *k3dProvider) Metadata(ctx context.Context, req provider.MetadataRequest
 func (p *k3dProvider) DataSources(context.Context) []func() datasource.DataSource {
 	return []func() datasource.DataSource{
 		NewNodesDataSource,
+		NewK3sVersionsDataSource,
 	}
 }
 
 func (p *k3dProvider) Resources(context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewClusterResource,
+		NewNetworkResource,
+		NewManifestResource,
+		NewHelmChartResource,
+		NewNodeExecResource,
+		NewClusterReadyResource,
 	}
 }
 
@@ -53,6 +67,7 @@ func New(version string) func() provider.Provider {
 	return func() provider.Provider {
 		return &k3dProvider{
 			version: version,
+			runtime: k3dClient{},
 		}
 	}
 }
@@ -1,12 +1,28 @@
 package provider
 
 import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-testing/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 )
 
+// testAccNamePrefix prefixes the names of the clusters and networks created by
+// acceptance tests, so that the sweepers can find the ones left behind by
+// failed runs.
+const testAccNamePrefix = "acc-test"
+
+// TestMain runs the sweepers instead of the tests if the -sweep flag is set.
+func TestMain(m *testing.M) {
+	resource.TestMain(m)
+}
+
 // testAccProtoV6ProviderFactories are used to instantiate a provider during
 // acceptance testing. The factory function will be invoked for every Terraform
 // CLI command executed to create a provider server to which the CLI can
@@ -15,6 +31,47 @@ var testAccProtoV6ProviderFactories = map[string]func() (tfprotov6.ProviderServe
 	"k3d": providerserver.NewProtocol6WithError(New("test")()),
 }
 
+// testUnitProtoV6ProviderFactories instantiate a provider that manages k3d
+// clusters through the given runtime, which lets unit tests replace the
+// container runtime with a fake.
+func testUnitProtoV6ProviderFactories(rt k3dRuntime) map[string]func() (tfprotov6.ProviderServer, error) {
+	return map[string]func() (tfprotov6.ProviderServer, error){
+		"k3d": providerserver.NewProtocol6WithError(&k3dProvider{version: "test", runtime: rt}),
+	}
+}
+
+// testUnitPreCheck skips unit tests that run Terraform when the Terraform CLI
+// is not available, as it would otherwise be downloaded.
+func testUnitPreCheck(t *testing.T) {
+	if os.Getenv("TF_ACC_TERRAFORM_PATH") != "" || os.Getenv("TF_ACC_TERRAFORM_VERSION") != "" {
+		return
+	}
+
+	if _, err := exec.LookPath("terraform"); err != nil {
+		t.Skip("Terraform CLI not found on PATH")
+	}
+}
+
+// testAccRandomName returns a unique name with the test prefix. The random
+// suffix is kept short, as k3d limits cluster names to 32 characters.
+func testAccRandomName(name string) string {
+	return fmt.Sprintf("%s-%s-%s", testAccNamePrefix, name, acctest.RandString(6))
+}
+
+// testAccRandomPort returns a random port for the Kubernetes API. The ports
+// are picked below the ephemeral port range to not conflict with the ports
+// bound by k3d.
+func testAccRandomPort() int {
+	return acctest.RandIntRange(20000, 30000)
+}
+
+// testSweepName reports whether a cluster, registry or network was created by
+// an acceptance test. k3d prefixes the names of the containers and networks it
+// creates for a cluster with "k3d-".
+func testSweepName(name string) bool {
+	return strings.HasPrefix(strings.TrimPrefix(name, "k3d-"), testAccNamePrefix)
+}
+
 func testAccPreCheck(t *testing.T) {
 	// You can add code here to run prior to any test case execution, for example assertions
 	// about the appropriate environment variables being set are common to see in a pre-check
@@ -0,0 +1,121 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// readinessPollInterval is how often the cluster is checked while waiting for
+// it to become ready.
+const readinessPollInterval = 2 * time.Second
+
+// defaultReadyDeployments are the deployments in kube-system that workloads
+// commonly depend on in a default K3s installation.
+var defaultReadyDeployments = []string{"coredns", "local-path-provisioner"}
+
+// readinessChecks configures what waitForClusterReady waits for.
+type readinessChecks struct {
+	Nodes       bool
+	Namespace   string
+	Deployments []string
+}
+
+// waitForClusterReady polls the cluster until all checks pass or the context
+// is done. It returns the names of the Ready nodes. Errors talking to the API
+// server are retried, as it may not be up yet or restart while waiting, and
+// the last one is only returned once the context is done.
+func waitForClusterReady(ctx context.Context, clientset kubernetes.Interface, checks readinessChecks, interval time.Duration) ([]string, error) {
+	var lastErr error
+	for {
+		ready, pending, err := checkClusterReady(ctx, clientset, checks)
+		if err != nil {
+			tflog.Debug(ctx, err.Error())
+			lastErr = err
+		}
+
+		if len(pending) == 0 {
+			return ready, nil
+		}
+
+		tflog.Debug(ctx, fmt.Sprintf("waiting for %s", strings.Join(pending, ", ")))
+
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return nil, fmt.Errorf("timed out waiting for %s: %w", strings.Join(pending, ", "), lastErr)
+			}
+			return nil, fmt.Errorf("timed out waiting for %s", strings.Join(pending, ", "))
+		case <-time.After(interval):
+		}
+	}
+}
+
+// checkClusterReady runs the readiness checks once. It returns the names of
+// the Ready nodes and a description of each check that has not passed yet.
+// Checks that fail with an error are reported as pending and the last error
+// is returned along with them.
+func checkClusterReady(ctx context.Context, clientset kubernetes.Interface, checks readinessChecks) ([]string, []string, error) {
+	var ready, pending []string
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, []string{"the API server"}, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	for _, node := range nodes.Items {
+		if nodeReady(&node) {
+			ready = append(ready, node.Name)
+		} else if checks.Nodes {
+			pending = append(pending, fmt.Sprintf("node %s", node.Name))
+		}
+	}
+	sort.Strings(ready)
+
+	if checks.Nodes && len(nodes.Items) == 0 {
+		pending = append(pending, "nodes to register")
+	}
+
+	var lastErr error
+	for _, name := range checks.Deployments {
+		deployment, err := clientset.AppsV1().Deployments(checks.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			lastErr = fmt.Errorf("failed to read deployment %s/%s: %w", checks.Namespace, name, err)
+		}
+
+		if err != nil || !deploymentAvailable(deployment) {
+			pending = append(pending, fmt.Sprintf("deployment %s/%s", checks.Namespace, name))
+		}
+	}
+
+	return ready, pending, lastErr
+}
+
+func nodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+func deploymentAvailable(deployment *appsv1.Deployment) bool {
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentAvailable {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,183 @@
+package provider
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func testNode(name string, status corev1.ConditionStatus) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: status},
+			},
+		},
+	}
+}
+
+func testDeployment(name string, status corev1.ConditionStatus) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system"},
+		Status: appsv1.DeploymentStatus{
+			Conditions: []appsv1.DeploymentCondition{
+				{Type: appsv1.DeploymentAvailable, Status: status},
+			},
+		},
+	}
+}
+
+func TestCheckClusterReady(t *testing.T) {
+	checks := readinessChecks{
+		Nodes:       true,
+		Namespace:   "kube-system",
+		Deployments: []string{"coredns", "local-path-provisioner", "traefik"},
+	}
+
+	clientset := fake.NewSimpleClientset(
+		testNode("k3d-test-server-0", corev1.ConditionTrue),
+		testNode("k3d-test-agent-0", corev1.ConditionFalse),
+		testDeployment("coredns", corev1.ConditionTrue),
+		testDeployment("local-path-provisioner", corev1.ConditionFalse),
+	)
+
+	ready, pending, err := checkClusterReady(context.Background(), clientset, checks)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := []string{"k3d-test-server-0"}; !reflect.DeepEqual(ready, expected) {
+		t.Errorf("ready nodes %v, expected %v", ready, expected)
+	}
+
+	expected := []string{
+		"node k3d-test-agent-0",
+		"deployment kube-system/local-path-provisioner",
+		"deployment kube-system/traefik",
+	}
+	if !reflect.DeepEqual(pending, expected) {
+		t.Errorf("pending checks %v, expected %v", pending, expected)
+	}
+
+	// not waiting for the nodes only leaves the deployments pending
+	checks.Nodes = false
+	_, pending, err = checkClusterReady(context.Background(), clientset, checks)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(pending) != 2 {
+		t.Errorf("pending checks %v, expected only the deployments", pending)
+	}
+}
+
+func TestWaitForClusterReady(t *testing.T) {
+	checks := readinessChecks{
+		Nodes:       true,
+		Namespace:   "kube-system",
+		Deployments: []string{"coredns"},
+	}
+
+	objects := []runtime.Object{
+		testNode("k3d-test-server-0", corev1.ConditionTrue),
+		testDeployment("coredns", corev1.ConditionTrue),
+	}
+
+	ready, err := waitForClusterReady(context.Background(), fake.NewSimpleClientset(objects...), checks, time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := []string{"k3d-test-server-0"}; !reflect.DeepEqual(ready, expected) {
+		t.Errorf("ready nodes %v, expected %v", ready, expected)
+	}
+}
+
+func TestWaitForClusterReadyTimeout(t *testing.T) {
+	checks := readinessChecks{
+		Nodes:     true,
+		Namespace: "kube-system",
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	clientset := fake.NewSimpleClientset(testNode("k3d-test-server-0", corev1.ConditionFalse))
+
+	_, err := waitForClusterReady(ctx, clientset, checks, time.Millisecond)
+	if err == nil {
+		t.Fatal("expected the wait to time out")
+	}
+
+	if !strings.Contains(err.Error(), "node k3d-test-server-0") {
+		t.Errorf("expected the error to name the pending node, got: %v", err)
+	}
+}
+
+func TestWaitForClusterReadyRetriesErrors(t *testing.T) {
+	checks := readinessChecks{
+		Nodes:       true,
+		Namespace:   "kube-system",
+		Deployments: []string{"coredns"},
+	}
+
+	clientset := fake.NewSimpleClientset(
+		testNode("k3d-test-server-0", corev1.ConditionTrue),
+		testDeployment("coredns", corev1.ConditionTrue),
+	)
+
+	// the API server is unavailable for the first requests, e.g. while it
+	// restarts
+	failures := 2
+	clientset.PrependReactor("get", "deployments", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if failures == 0 {
+			return false, nil, nil
+		}
+		failures--
+		return true, nil, apierrors.NewServiceUnavailable("restarting")
+	})
+
+	ready, err := waitForClusterReady(context.Background(), clientset, checks, time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := []string{"k3d-test-server-0"}; !reflect.DeepEqual(ready, expected) {
+		t.Errorf("ready nodes %v, expected %v", ready, expected)
+	}
+}
+
+func TestWaitForClusterReadyTimeoutError(t *testing.T) {
+	checks := readinessChecks{
+		Namespace:   "kube-system",
+		Deployments: []string{"coredns"},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("get", "deployments", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewServiceUnavailable("restarting")
+	})
+
+	_, err := waitForClusterReady(ctx, clientset, checks, time.Millisecond)
+	if err == nil {
+		t.Fatal("expected the wait to time out")
+	}
+
+	if !strings.Contains(err.Error(), "deployment kube-system/coredns") || !apierrors.IsServiceUnavailable(err) {
+		t.Errorf("expected the error to name the deployment and wrap the last error, got: %v", err)
+	}
+}
@@ -4,56 +4,120 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
-	"path"
+	"net"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/docker/go-connections/nat"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/objectplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 	client "github.com/k3d-io/k3d/v5/pkg/client"
 	confutils "github.com/k3d-io/k3d/v5/pkg/config"
 	conftypes "github.com/k3d-io/k3d/v5/pkg/config/types"
 	config "github.com/k3d-io/k3d/v5/pkg/config/v1alpha5"
-	"github.com/k3d-io/k3d/v5/pkg/runtimes"
+	runtimeerrors "github.com/k3d-io/k3d/v5/pkg/runtimes/errors"
 	k3dtypes "github.com/k3d-io/k3d/v5/pkg/types"
-	k3dutil "github.com/k3d-io/k3d/v5/pkg/util"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces
-var _ resource.Resource = k3dCluster{}
+var _ resource.Resource = &k3dCluster{}
+var _ resource.ResourceWithConfigure = &k3dCluster{}
+var _ resource.ResourceWithModifyPlan = &k3dCluster{}
 
 func NewClusterResource() resource.Resource {
-	return k3dCluster{}
+	return &k3dCluster{}
 }
 
 type k3dClusterData struct {
-	ID          types.String `tfsdk:"id"`
-	Name        types.String `tfsdk:"name"`
-	Servers     types.Int64  `tfsdk:"servers"`
-	Agents      types.Int64  `tfsdk:"agents"`
-	K8sHost     types.String `tfsdk:"k8s_api_host"`
-	K8sHostIP   types.String `tfsdk:"k8s_api_host_ip"`
-	K8sHostPort types.Int64  `tfsdk:"k8s_api_host_port"`
-	Image       types.String `tfsdk:"image"`
-	ImageSHA    types.String `tfsdk:"image_sha"`
-	Network     types.String `tfsdk:"network"`
+	ID             types.String `tfsdk:"id"`
+	Name           types.String `tfsdk:"name"`
+	Servers        types.Int64  `tfsdk:"servers"`
+	Agents         types.Int64  `tfsdk:"agents"`
+	K8sHost        types.String `tfsdk:"k8s_api_host"`
+	K8sHostIP      types.String `tfsdk:"k8s_api_host_ip"`
+	K8sHostPort    types.Int64  `tfsdk:"k8s_api_host_port"`
+	K8sBoundPort   types.Int64  `tfsdk:"k8s_api_bound_port"`
+	Image          types.String `tfsdk:"image"`
+	K3sVersion     types.String `tfsdk:"k3s_version"`
+	Upgrade        types.String `tfsdk:"upgrade_strategy"`
+	ImageSHA       types.String `tfsdk:"image_sha"`
+	Network        types.String `tfsdk:"network"`
+	Subnet         types.String `tfsdk:"subnet"`
+	ClusterCIDRs   types.List   `tfsdk:"cluster_cidrs"`
+	ServiceCIDRs   types.List   `tfsdk:"service_cidrs"`
+	NetworkCreated types.Bool   `tfsdk:"network_created"`
+	Loadbalancer   types.Object `tfsdk:"loadbalancer"`
+	HostAliases    types.List   `tfsdk:"host_aliases"`
+	Token          types.String `tfsdk:"token"`
+	Files          types.List   `tfsdk:"file"`
+	KeepOnFailure  types.Bool   `tfsdk:"keep_on_failure"`
+}
+
+type k3dHostAliasData struct {
+	IP        types.String `tfsdk:"ip"`
+	Hostnames types.List   `tfsdk:"hostnames"`
+}
+
+var hostAliasAttrTypes = map[string]attr.Type{
+	"ip":        types.StringType,
+	"hostnames": types.ListType{ElemType: types.StringType},
+}
+
+type k3dLoadbalancerData struct {
+	Enabled         types.Bool `tfsdk:"enabled"`
+	ConfigOverrides types.List `tfsdk:"config_overrides"`
+	PortMap         types.Map  `tfsdk:"port_map"`
+}
+
+var loadbalancerAttrTypes = map[string]attr.Type{
+	"enabled":          types.BoolType,
+	"config_overrides": types.ListType{ElemType: types.StringType},
+	"port_map":         types.MapType{ElemType: types.ListType{ElemType: types.StringType}},
 }
 
 type k3dCluster struct {
+	runtime k3dRuntime
+}
+
+func (c *k3dCluster) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// the provider is not configured yet during validation
+	if req.ProviderData == nil {
+		return
+	}
+
+	rt, ok := req.ProviderData.(k3dRuntime)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected a k3d runtime, got %T. This is always a bug in the provider code and should be reported to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	c.runtime = rt
 }
 
 func (k3dCluster) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -81,6 +145,9 @@ func (k3dCluster) Schema(ctx context.Context, req resource.SchemaRequest, resp *
 					int64planmodifier.RequiresReplace(),
 				},
 				Default: int64default.StaticInt64(1),
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
 			},
 			"agents": schema.Int64Attribute{
 				MarkdownDescription: "Number of agents to create",
@@ -90,25 +157,23 @@ func (k3dCluster) Schema(ctx context.Context, req resource.SchemaRequest, resp *
 					int64planmodifier.RequiresReplace(),
 				},
 				Default: int64default.StaticInt64(0),
+				Validators: []validator.Int64{
+					int64validator.AtLeast(0),
+				},
 			},
 			"k8s_api_host": schema.StringAttribute{
-				MarkdownDescription: "The hostname to serve the Kubernetes APIs with",
+				MarkdownDescription: "The hostname or IP address to serve the Kubernetes APIs with. It is written to the kubeconfig and added to the TLS certificate of the API server.",
 				Optional:            true,
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.UseStateForUnknown(),
 					stringplanmodifier.RequiresReplace(),
 				},
 				Validators: []validator.String{
-					// These are example validators from terraform-plugin-framework-validators
-					stringvalidator.LengthBetween(10, 256),
-					stringvalidator.RegexMatches(
-						regexp.MustCompile(`^[a-z0-9]+$`),
-						"must contain only lowercase alphanumeric characters",
-					),
+					validateHost,
 				},
 			},
 			"k8s_api_host_ip": schema.StringAttribute{
-				MarkdownDescription: "The IP to bind the Kubernetes API",
+				MarkdownDescription: "The IP to bind the Kubernetes API. IPv6 addresses may be enclosed in square brackets (e.g. `[::1]`).",
 				Optional:            true,
 				Computed:            true,
 				PlanModifiers: []planmodifier.String{
@@ -117,11 +182,11 @@ func (k3dCluster) Schema(ctx context.Context, req resource.SchemaRequest, resp *
 				},
 				Default: stringdefault.StaticString("127.0.0.1"),
 				Validators: []validator.String{
-					validateIP,
+					validateHostIP,
 				},
 			},
 			"k8s_api_host_port": schema.Int64Attribute{
-				MarkdownDescription: "The port to bind the Kubernetes API",
+				MarkdownDescription: "The port to bind the Kubernetes API. Set to `0` to bind a free port, which is reported in `k8s_api_bound_port`. Defaults to `6550`",
 				Optional:            true,
 				Computed:            true,
 				PlanModifiers: []planmodifier.Int64{
@@ -130,7 +195,14 @@ func (k3dCluster) Schema(ctx context.Context, req resource.SchemaRequest, resp *
 				},
 				Default: int64default.StaticInt64(6550),
 				Validators: []validator.Int64{
-					validatePort,
+					validateAPIPort,
+				},
+			},
+			"k8s_api_bound_port": schema.Int64Attribute{
+				MarkdownDescription: "The port the Kubernetes API is bound to on the host. This is the free port that was picked if `k8s_api_host_port` is `0`.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
 				},
 			},
 			"image": schema.StringAttribute{
@@ -138,16 +210,44 @@ func (k3dCluster) Schema(ctx context.Context, req resource.SchemaRequest, resp *
 				Optional:            true,
 				Computed:            true,
 				PlanModifiers: []planmodifier.String{
+					imageFromK3sVersion,
 					stringplanmodifier.UseStateForUnknown(),
-					stringplanmodifier.RequiresReplace(),
+					requiresReplaceIfRecreate,
 				},
 				Default: stringdefault.StaticString("latest"),
 			},
+			"k3s_version": schema.StringAttribute{
+				MarkdownDescription: "K3s version to run (e.g. `v1.29.2+k3s1`). This is translated into the corresponding `rancher/k3s` image and conflicts with `image`. If unset, the version is reported from the running server nodes.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					unknownOnImageChange,
+					requiresReplaceIfRecreate,
+				},
+				Validators: []validator.String{
+					stringvalidator.ConflictsWith(path.MatchRoot("image")),
+					stringvalidator.RegexMatches(
+						k3sVersionRegex,
+						"must be a K3s release version such as v1.29.2+k3s1",
+					),
+				},
+			},
 			"image_sha": schema.StringAttribute{
 				MarkdownDescription: "SHA of the docker image that was used",
 				Computed:            true,
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.UseStateForUnknown(),
+					unknownOnImageChange,
+				},
+			},
+			"upgrade_strategy": schema.StringAttribute{
+				MarkdownDescription: "How changes to `image` or `k3s_version` are applied. `recreate` replaces the whole cluster while `rolling` replaces the server nodes one at a time followed by the agents, waiting for each node to become Ready and preserving the K3s datastore.",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(upgradeStrategyRecreate),
+				Validators: []validator.String{
+					stringvalidator.OneOf(upgradeStrategyRecreate, upgradeStrategyRolling),
 				},
 			},
 			"network": schema.StringAttribute{
@@ -159,14 +259,282 @@ func (k3dCluster) Schema(ctx context.Context, req resource.SchemaRequest, resp *
 					stringplanmodifier.RequiresReplace(),
 				},
 			},
+			"subnet": schema.StringAttribute{
+				MarkdownDescription: "IPv4 subnet of the cluster network in CIDR notation (e.g. `172.28.0.0/16`). If unset, the runtime picks a free subnet when creating the network. Use a `k3d_network` to attach the cluster to a dual-stack network.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					validateIPv4CIDR,
+				},
+			},
+			"cluster_cidrs": schema.ListAttribute{
+				MarkdownDescription: "Pod network prefixes passed to K3s as `--cluster-cidr`. Set an IPv4 and an IPv6 prefix for a dual-stack cluster (e.g. `[\"10.42.0.0/16\", \"fd00:42::/56\"]`).",
+				Optional:            true,
+				ElementType:         types.StringType,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.List{
+					listvalidator.SizeBetween(1, 2),
+					listvalidator.ValueStringsAre(validateCIDR),
+					validateDualStack,
+				},
+			},
+			"service_cidrs": schema.ListAttribute{
+				MarkdownDescription: "Service network prefixes passed to K3s as `--service-cidr`. Set an IPv4 and an IPv6 prefix for a dual-stack cluster (e.g. `[\"10.43.0.0/16\", \"fd00:43::/112\"]`).",
+				Optional:            true,
+				ElementType:         types.StringType,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.List{
+					listvalidator.SizeBetween(1, 2),
+					listvalidator.ValueStringsAre(validateCIDR),
+					validateDualStack,
+				},
+			},
+			"network_created": schema.BoolAttribute{
+				MarkdownDescription: "Whether the network was created along with the cluster. Only networks created along with the cluster are removed when the cluster is destroyed.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"loadbalancer": schema.SingleNestedAttribute{
+				MarkdownDescription: "Settings of the load balancer placed in front of the server nodes",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.Object{
+					objectplanmodifier.UseStateForUnknown(),
+				},
+				Attributes: map[string]schema.Attribute{
+					"enabled": schema.BoolAttribute{
+						MarkdownDescription: "Whether to create the load balancer. Defaults to `true`",
+						Optional:            true,
+						Computed:            true,
+						Default:             booldefault.StaticBool(true),
+						PlanModifiers: []planmodifier.Bool{
+							boolplanmodifier.RequiresReplace(),
+						},
+					},
+					"config_overrides": schema.ListAttribute{
+						MarkdownDescription: "Overrides for the load balancer configuration in the form of `key=value` (e.g. `settings.workerConnections=2048`)",
+						Optional:            true,
+						ElementType:         types.StringType,
+						PlanModifiers: []planmodifier.List{
+							listplanmodifier.RequiresReplace(),
+						},
+					},
+					"port_map": schema.MapAttribute{
+						MarkdownDescription: "Map of the ports proxied by the load balancer (e.g. `6443.tcp`) to the names of the nodes they are forwarded to",
+						Computed:            true,
+						ElementType:         types.ListType{ElemType: types.StringType},
+						PlanModifiers: []planmodifier.Map{
+							mapplanmodifier.UseStateForUnknown(),
+						},
+					},
+				},
+			},
+			"host_aliases": schema.ListNestedAttribute{
+				MarkdownDescription: "Additional entries injected into `/etc/hosts` of the nodes and into the CoreDNS configuration of the cluster",
+				Optional:            true,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"ip": schema.StringAttribute{
+							MarkdownDescription: "The IP address the hostnames resolve to",
+							Required:            true,
+							Validators: []validator.String{
+								validateIP,
+							},
+						},
+						"hostnames": schema.ListAttribute{
+							MarkdownDescription: "The hostnames that resolve to the IP address",
+							Required:            true,
+							ElementType:         types.StringType,
+							Validators: []validator.List{
+								listvalidator.SizeAtLeast(1),
+							},
+						},
+					},
+				},
+			},
+			"keep_on_failure": schema.BoolAttribute{
+				MarkdownDescription: "Whether to keep the nodes of the cluster for debugging when it fails to be created, instead of rolling back. The kept cluster is replaced by the next apply. Defaults to `false`",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"token": schema.StringAttribute{
+				MarkdownDescription: "Token used by nodes to join the cluster. If unset, k3d generates a random token. This can be used to join external K3s agents to the cluster.",
+				Optional:            true,
+				Computed:            true,
+				Sensitive:           true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
 			"id": schema.StringAttribute{
 				MarkdownDescription: "The ID of the cluster",
 				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+
+		Blocks: map[string]schema.Block{
+			"file": schema.ListNestedBlock{
+				MarkdownDescription: "File to copy into the node containers before they are started, e.g. an auto-deploy manifest placed in `/var/lib/rancher/k3s/server/manifests`",
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"content": schema.StringAttribute{
+							MarkdownDescription: "Content of the file. Conflicts with `source`",
+							Optional:            true,
+							Validators: []validator.String{
+								stringvalidator.ExactlyOneOf(path.MatchRelative().AtParent().AtName("source")),
+							},
+						},
+						"source": schema.StringAttribute{
+							MarkdownDescription: "Path of a local file to copy. Conflicts with `content`",
+							Optional:            true,
+						},
+						"destination": schema.StringAttribute{
+							MarkdownDescription: "Absolute path of the file within the node containers",
+							Required:            true,
+							Validators: []validator.String{
+								stringvalidator.RegexMatches(regexp.MustCompile(`^/`), "must be an absolute path"),
+							},
+						},
+						"node_filters": schema.ListAttribute{
+							MarkdownDescription: "k3d node filters selecting the nodes to copy the file into (e.g. `server:0`, `agent:*`). Defaults to all server and agent nodes",
+							Optional:            true,
+							ElementType:         types.StringType,
+						},
+						"content_hash": schema.StringAttribute{
+							MarkdownDescription: "SHA-256 hash of the file content. Changes to the content cause the cluster to be replaced",
+							Computed:            true,
+							PlanModifiers: []planmodifier.String{
+								hashFileContent,
+								stringplanmodifier.RequiresReplace(),
+							},
+						},
+					},
+				},
 			},
 		},
 	}
 }
 
+// ModifyPlan validates the configuration of clusters that are about to be
+// created and reports host ports that are already in use, so that these
+// errors are surfaced at plan time instead of halfway through creating the
+// cluster.
+func (c k3dCluster) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan k3dClusterData
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// the configuration of an existing cluster is only used again if the
+	// cluster is replaced, and unknown values would be validated as if they
+	// were unset
+	creating := req.State.Raw.IsNull() || len(resp.RequiresReplace) > 0
+	if creating && req.Config.Raw.IsFullyKnown() {
+		conf := plan
+		// k3d resolves release channels over the network, which should not be
+		// needed to plan
+		if isK3sChannelImage(conf.Image.ValueString()) {
+			conf.Image = types.StringValue(k3sDefaultImage())
+		}
+
+		_, diags := buildClusterConfig(ctx, c.runtime, &conf)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	var state *k3dClusterData
+	if !req.State.Raw.IsNull() {
+		state = &k3dClusterData{}
+		resp.Diagnostics.Append(req.State.Get(ctx, state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	resp.Diagnostics.Append(checkPortConflicts(ctx, c.runtime, &plan, state)...)
+}
+
+// checkPortConflicts reports the planned Kubernetes API port as a conflict if
+// it is bound by another cluster or by another process on the host.
+func checkPortConflicts(ctx context.Context, rt k3dRuntime, plan *k3dClusterData, state *k3dClusterData) diag.Diagnostics {
+	var diagnostics diag.Diagnostics
+
+	// a free port is only picked when the port is bound
+	if plan.Name.IsUnknown() || plan.K8sHostIP.IsUnknown() || plan.K8sHostPort.IsUnknown() || plan.K8sHostPort.IsNull() || plan.K8sHostPort.ValueInt64() == 0 {
+		return diagnostics
+	}
+
+	// an existing cluster already holds its port
+	if state != nil && state.K8sHostIP.Equal(plan.K8sHostIP) && state.K8sHostPort.Equal(plan.K8sHostPort) {
+		return diagnostics
+	}
+
+	binding := nat.PortBinding{
+		HostIP:   unbracketIP(plan.K8sHostIP.ValueString()),
+		HostPort: plan.K8sHostPort.String(),
+	}
+	address := net.JoinHostPort(binding.HostIP, binding.HostPort)
+
+	nodes, err := rt.NodeList(ctx)
+	if err != nil {
+		diagnostics.Append(diag.NewWarningDiagnostic("Unable to check for port conflicts", err.Error()))
+		return diagnostics
+	}
+
+	for _, conflict := range findPortConflicts(nodes, plan.Name.ValueString(), binding) {
+		diagnostics.AddAttributeError(
+			path.Root("k8s_api_host_port"),
+			"Port already in use",
+			fmt.Sprintf("The Kubernetes API cannot be bound to %s, the port is already bound by node %s of cluster %q. Pick another port or set it to 0 to bind a free port, which is reported in k8s_api_bound_port.", address, conflict.Node, conflict.Cluster),
+		)
+	}
+
+	if diagnostics.HasError() {
+		return diagnostics
+	}
+
+	if rt.HostPortInUse(binding) {
+		diagnostics.AddAttributeError(
+			path.Root("k8s_api_host_port"),
+			"Port already in use",
+			fmt.Sprintf("The Kubernetes API cannot be bound to %s, the port is already in use on the host. Pick another port or set it to 0 to bind a free port, which is reported in k8s_api_bound_port.", address),
+		)
+	}
+
+	return diagnostics
+}
+
 func (c k3dCluster) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
 	var data k3dClusterData
 
@@ -177,12 +545,102 @@ func (c k3dCluster) Create(ctx context.Context, req resource.CreateRequest, resp
 		return
 	}
 
-	_, err := client.ClusterGet(ctx, runtimes.SelectedRuntime, &k3dtypes.Cluster{Name: data.Name.ValueString()})
+	ctx = tflog.SetField(ctx, "cluster", data.Name.ValueString())
+
+	_, err := c.runtime.ClusterGet(ctx, data.Name.ValueString())
 	if err == nil {
-		resp.Diagnostics.Append(diag.NewErrorDiagnostic("A cluster with the same name already exists", data.Name.ValueString()))
+		resp.Diagnostics.AddAttributeError(
+			path.Root("name"),
+			"A cluster with the same name already exists",
+			fmt.Sprintf("Cluster %q already exists. Import it into the state or choose another name.", data.Name.ValueString()),
+		)
+		return
+	}
+
+	networkCreated := true
+	if !data.Network.IsNull() && !data.Network.IsUnknown() {
+		_, err := c.runtime.NetworkGet(ctx, data.Network.ValueString())
+		if err == nil {
+			networkCreated = false
+		} else if !errors.Is(err, runtimeerrors.ErrRuntimeNetworkNotExists) {
+			resp.Diagnostics.AddAttributeError(path.Root("network"), "Error reading network", err.Error())
+			return
+		}
+	}
+
+	// the configured port is kept, the picked port is read back from the
+	// cluster into k8s_api_bound_port
+	configData := data
+	if data.K8sHostPort.IsUnknown() || data.K8sHostPort.ValueInt64() == 0 {
+		port, err := c.runtime.FreePort()
+		if err != nil {
+			resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error picking a free port for the Kubernetes API", err.Error()))
+			return
+		}
+
+		configData.K8sHostPort = types.Int64Value(int64(port))
+	}
+
+	clusterConfig, diags := buildClusterConfig(ctx, c.runtime, &configData)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Info(ctx, "creating k3d cluster")
+	err = c.runtime.ClusterRun(ctx, clusterConfig)
+	if err != nil {
+		// collect the logs before the rollback removes the nodes
+		resp.Diagnostics.Append(clusterErrorDiagnostic("Error creating cluster", err, &data, clusterNodeLogs(ctx, c.runtime, data.Name.ValueString())...))
+
+		if data.KeepOnFailure.ValueBool() {
+			resp.Diagnostics.AddWarning(
+				"Failed cluster kept",
+				fmt.Sprintf("Cluster %q was kept for debugging as keep_on_failure is set. It is replaced by the next apply, or can be deleted with `k3d cluster delete %s`.", data.Name.ValueString(), data.Name.ValueString()),
+			)
+
+			// tracking the cluster as tainted lets Terraform replace it
+			data.NetworkCreated = types.BoolValue(networkCreated)
+			// the cluster may be incomplete, so reading it is best effort
+			_ = c.readCluster(ctx, &data)
+			clearUnknownValues(&data)
+
+			resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+			return
+		}
+
+		if err := c.runtime.ClusterDelete(ctx, &clusterConfig.Cluster, k3dtypes.ClusterDeleteOpts{SkipRegistryCheck: true}); err != nil {
+			resp.Diagnostics.Append(diag.NewWarningDiagnostic("Error rolling back failed cluster creation", err.Error()))
+		}
+		return
+	}
+	tflog.Info(ctx, "cluster successfully created")
+
+	tflog.Trace(ctx, "updating kubeconfig")
+	if err := c.runtime.KubeconfigWrite(ctx, &clusterConfig.Cluster); err != nil {
+		resp.Diagnostics.Append(diag.NewWarningDiagnostic("Error writing kubeconfig", err.Error()))
+	}
+
+	data.NetworkCreated = types.BoolValue(networkCreated)
+
+	resp.Diagnostics.Append(c.readCluster(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+// buildClusterConfig translates the resource data into a k3d cluster
+// configuration validated by the runtime. Unknown values are treated as
+// unset.
+func buildClusterConfig(ctx context.Context, rt k3dRuntime, data *k3dClusterData) (*config.ClusterConfig, diag.Diagnostics) {
+	var diagnostics diag.Diagnostics
+
+	// k3d logs while transforming and validating the configuration
+	defer withK3dLogContext(ctx)()
+
 	tflog.Trace(ctx, "synthesizing configuration")
 	simpleConf := config.SimpleConfig{
 		Servers: int(data.Servers.ValueInt64()),
@@ -199,80 +657,188 @@ func (c k3dCluster) Create(ctx context.Context, req resource.CreateRequest, resp
 		},
 	}
 
-	if !data.Network.IsNull() {
+	if !data.Loadbalancer.IsNull() && !data.Loadbalancer.IsUnknown() {
+		var lb k3dLoadbalancerData
+		diagnostics.Append(data.Loadbalancer.As(ctx, &lb, basetypes.ObjectAsOptions{})...)
+		if diagnostics.HasError() {
+			return nil, diagnostics
+		}
+
+		simpleConf.Options.K3dOptions.DisableLoadbalancer = !lb.Enabled.IsNull() && !lb.Enabled.ValueBool()
+		diagnostics.Append(lb.ConfigOverrides.ElementsAs(ctx, &simpleConf.Options.K3dOptions.Loadbalancer.ConfigOverrides, false)...)
+		if diagnostics.HasError() {
+			return nil, diagnostics
+		}
+	}
+
+	if !data.HostAliases.IsNull() && !data.HostAliases.IsUnknown() {
+		var aliases []k3dHostAliasData
+		diagnostics.Append(data.HostAliases.ElementsAs(ctx, &aliases, false)...)
+		if diagnostics.HasError() {
+			return nil, diagnostics
+		}
+
+		for _, alias := range aliases {
+			hostAlias := k3dtypes.HostAlias{IP: alias.IP.ValueString()}
+			diagnostics.Append(alias.Hostnames.ElementsAs(ctx, &hostAlias.Hostnames, false)...)
+			simpleConf.HostAliases = append(simpleConf.HostAliases, hostAlias)
+		}
+
+		if diagnostics.HasError() {
+			return nil, diagnostics
+		}
+	}
+
+	if !data.Network.IsNull() && !data.Network.IsUnknown() {
 		simpleConf.Network = data.Network.ValueString()
 	}
 
+	if !data.Subnet.IsNull() && !data.Subnet.IsUnknown() {
+		simpleConf.Subnet = data.Subnet.ValueString()
+	}
+
+	for _, cidrs := range []struct {
+		arg   string
+		value types.List
+	}{
+		{"--cluster-cidr", data.ClusterCIDRs},
+		{"--service-cidr", data.ServiceCIDRs},
+	} {
+		if cidrs.value.IsNull() || cidrs.value.IsUnknown() {
+			continue
+		}
+
+		var prefixes []string
+		diagnostics.Append(cidrs.value.ElementsAs(ctx, &prefixes, false)...)
+		if diagnostics.HasError() {
+			return nil, diagnostics
+		}
+
+		simpleConf.Options.K3sOptions.ExtraArgs = append(simpleConf.Options.K3sOptions.ExtraArgs, config.K3sArgWithNodeFilters{
+			Arg:         fmt.Sprintf("%s=%s", cidrs.arg, strings.Join(prefixes, ",")),
+			NodeFilters: []string{"server:*"},
+		})
+	}
+
+	if !data.Token.IsNull() && !data.Token.IsUnknown() {
+		simpleConf.ClusterToken = data.Token.ValueString()
+	}
+
 	if !data.K8sHost.IsNull() {
-		simpleConf.ExposeAPI.Host = data.K8sHost.ValueString()
+		host := unbracketIP(data.K8sHost.ValueString())
+		simpleConf.ExposeAPI.Host = host
+
+		// make sure the API server certificate is valid for the host the
+		// kubeconfig points to
+		simpleConf.Options.K3sOptions.ExtraArgs = append(simpleConf.Options.K3sOptions.ExtraArgs, config.K3sArgWithNodeFilters{
+			Arg:         "--tls-san=" + host,
+			NodeFilters: []string{"server:*"},
+		})
 	}
 
 	if !data.K8sHostIP.IsNull() {
-		simpleConf.ExposeAPI.HostIP = data.K8sHostIP.ValueString()
+		simpleConf.ExposeAPI.HostIP = unbracketIP(data.K8sHostIP.ValueString())
 	}
 
-	if !data.K8sHostPort.IsNull() {
+	// a free port is only picked when the cluster is created
+	if !data.K8sHostPort.IsNull() && !data.K8sHostPort.IsUnknown() && data.K8sHostPort.ValueInt64() != 0 {
 		simpleConf.ExposeAPI.HostPort = data.K8sHostPort.String()
 	}
 
 	tflog.Trace(ctx, "normalizing configuration")
 	if err := confutils.ProcessSimpleConfig(&simpleConf); err != nil {
-		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error processing K3D simple configuration", err.Error()))
-		return
+		diagnostics.Append(clusterErrorDiagnostic("Error processing K3D simple configuration", err, data))
+		return nil, diagnostics
 	}
 
 	tflog.Trace(ctx, "generating k3d cluster configuration from simple config")
-	clusterConfig, err := confutils.TransformSimpleToClusterConfig(ctx, runtimes.SelectedRuntime, simpleConf)
+	clusterConfig, err := rt.ClusterConfig(ctx, simpleConf)
 	if err != nil {
-		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error transforming simple config to cluster config", err.Error()))
-		return
+		diagnostics.Append(clusterErrorDiagnostic("Error transforming simple config to cluster config", err, data))
+		return nil, diagnostics
+	}
+
+	if !data.Files.IsNull() && !data.Files.IsUnknown() {
+		var files []k3dFileData
+		diagnostics.Append(data.Files.ElementsAs(ctx, &files, false)...)
+		if diagnostics.HasError() {
+			return nil, diagnostics
+		}
+
+		for idx, file := range files {
+			filePath := path.Root("file").AtListIndex(idx)
+
+			content, err := fileContent(file.Content, file.Source)
+			if err != nil {
+				diagnostics.AddAttributeError(filePath, "Error reading file content", err.Error())
+				return nil, diagnostics
+			}
+
+			var nodeFilters []string
+			diagnostics.Append(file.NodeFilters.ElementsAs(ctx, &nodeFilters, false)...)
+			if diagnostics.HasError() {
+				return nil, diagnostics
+			}
+
+			if err := injectFile(clusterConfig, content, file.Destination.ValueString(), nodeFilters); err != nil {
+				diagnostics.AddAttributeError(filePath.AtName("node_filters"), "Error injecting file", err.Error())
+				return nil, diagnostics
+			}
+		}
 	}
 
 	tflog.Trace(ctx, "normalizing cluster configuration")
 	clusterConfig, err = confutils.ProcessClusterConfig(*clusterConfig)
 	if err != nil {
-		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error processing cluster config", err.Error()))
-		return
+		diagnostics.Append(clusterErrorDiagnostic("Error processing cluster config", err, data))
+		return nil, diagnostics
 	}
 
 	tflog.Trace(ctx, "validating cluster configuration")
-	err = confutils.ValidateClusterConfig(ctx, runtimes.SelectedRuntime, *clusterConfig)
+	err = rt.ClusterConfigValidate(ctx, clusterConfig)
 	if err != nil {
-		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error validating cluster config", err.Error()))
-		return
+		diagnostics.Append(clusterErrorDiagnostic("Error validating cluster config", err, data))
+		return nil, diagnostics
 	}
 
-	tflog.Info(ctx, "creating k3d cluster")
-	err = client.ClusterRun(ctx, runtimes.SelectedRuntime, clusterConfig)
-	if err != nil {
-		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error creating cluster", err.Error()))
-		if err := client.ClusterDelete(ctx, runtimes.SelectedRuntime, &clusterConfig.Cluster, k3dtypes.ClusterDeleteOpts{SkipRegistryCheck: true}); err != nil {
-			resp.Diagnostics.Append(diag.NewWarningDiagnostic("Error rolling back failed cluster creation", err.Error()))
-		}
-		return
-	}
-	tflog.Info(ctx, "cluster successfully created")
+	return clusterConfig, diagnostics
+}
 
-	tflog.Trace(ctx, "updating kubeconfig")
-	_, err = client.KubeconfigGetWrite(ctx, runtimes.SelectedRuntime, &clusterConfig.Cluster, "", &client.WriteKubeConfigOptions{UpdateExisting: true, OverwriteExisting: false})
-	if err != nil {
-		resp.Diagnostics.Append(diag.NewWarningDiagnostic("Error writing kubeconfig", err.Error()))
+// clearUnknownValues nulls the computed values that could not be read from
+// a cluster that failed to be created, as they must be known in the state.
+func clearUnknownValues(data *k3dClusterData) {
+	data.ID = data.Name
+	if data.ImageSHA.IsUnknown() {
+		data.ImageSHA = types.StringNull()
 	}
-
-	resp.Diagnostics.Append(c.readCluster(ctx, &data)...)
-	if resp.Diagnostics.HasError() {
-		return
+	if data.K3sVersion.IsUnknown() {
+		data.K3sVersion = types.StringNull()
+	}
+	if data.Network.IsUnknown() {
+		data.Network = types.StringNull()
+	}
+	if data.Subnet.IsUnknown() {
+		data.Subnet = types.StringNull()
+	}
+	if data.Token.IsUnknown() {
+		data.Token = types.StringNull()
+	}
+	if data.K8sHostIP.IsUnknown() {
+		data.K8sHostIP = types.StringNull()
+	}
+	if data.K8sBoundPort.IsUnknown() {
+		data.K8sBoundPort = types.Int64Null()
+	}
+	if data.Loadbalancer.IsUnknown() {
+		data.Loadbalancer = types.ObjectNull(loadbalancerAttrTypes)
 	}
-
-	diags = resp.State.Set(ctx, &data)
-	resp.Diagnostics.Append(diags...)
 }
 
-func (k3dCluster) readCluster(ctx context.Context, data *k3dClusterData) diag.Diagnostics {
+func (c k3dCluster) readCluster(ctx context.Context, data *k3dClusterData) diag.Diagnostics {
 	var diagnostics diag.Diagnostics
 
 	tflog.Info(ctx, fmt.Sprintf("reading cluster: %s", data.Name.ValueString()))
-	cluster, err := client.ClusterGet(ctx, runtimes.SelectedRuntime, &k3dtypes.Cluster{Name: data.Name.ValueString()})
+	cluster, err := c.runtime.ClusterGet(ctx, data.Name.ValueString())
 	if err != nil {
 		diagnostics.Append(diag.NewErrorDiagnostic("Error reading k3d cluster", err.Error()))
 		return diagnostics
@@ -281,6 +847,7 @@ func (k3dCluster) readCluster(ctx context.Context, data *k3dClusterData) diag.Di
 	agentCount := 0
 	serverCount := 0
 	images := make(map[string]struct{})
+	var server *k3dtypes.Node
 	for _, node := range cluster.Nodes {
 		if node.Role != k3dtypes.AgentRole && node.Role != k3dtypes.ServerRole {
 			continue
@@ -292,9 +859,26 @@ func (k3dCluster) readCluster(ctx context.Context, data *k3dClusterData) diag.Di
 			agentCount++
 		} else if node.Role == k3dtypes.ServerRole {
 			serverCount++
+			server = node
 		}
 	}
 
+	// k3d reports the image ID on the nodes, the version can only be taken
+	// from the reference the server was created from
+	serverImage := ""
+	if server != nil {
+		serverImage, err = c.runtime.NodeImage(ctx, server)
+		if err != nil {
+			diagnostics.Append(diag.NewWarningDiagnostic("Error reading server image", err.Error()))
+		}
+	}
+
+	if version, ok := imageToK3sVersion(serverImage); ok {
+		data.K3sVersion = types.StringValue(version)
+	} else if data.K3sVersion.IsUnknown() {
+		data.K3sVersion = types.StringNull()
+	}
+
 	if len(images) > 1 {
 		var buf strings.Builder
 
@@ -316,13 +900,76 @@ func (k3dCluster) readCluster(ctx context.Context, data *k3dClusterData) diag.Di
 	data.Network = types.StringValue(cluster.Network.Name)
 	data.ID = data.Name
 
-	if cluster.KubeAPI != nil {
-		data.K8sHost = types.StringValue(cluster.KubeAPI.Host)
-		data.K8sHostIP = types.StringValue(cluster.KubeAPI.Binding.HostIP)
-		port, err := strconv.ParseInt(cluster.KubeAPI.Binding.HostPort, 10, 16)
-		if err != nil {
-			diagnostics.Append(diag.NewWarningDiagnostic("Invalid port found in cluster settings", cluster.KubeAPI.Binding.HostPort))
-		} else {
+	if cluster.Token != "" {
+		data.Token = types.StringValue(cluster.Token)
+	} else if data.Token.IsUnknown() {
+		data.Token = types.StringNull()
+	}
+
+	network, err := c.runtime.NetworkGet(ctx, cluster.Network.Name)
+	if err != nil {
+		diagnostics.Append(diag.NewWarningDiagnostic("Error reading cluster network", err.Error()))
+	} else if network.IPAM.IPPrefix.IsValid() {
+		data.Subnet = types.StringValue(network.IPAM.IPPrefix.String())
+	}
+
+	if data.Subnet.IsUnknown() {
+		data.Subnet = types.StringNull()
+	}
+
+	diagnostics.Append(readLoadbalancer(ctx, cluster, data)...)
+	diagnostics.Append(readHostAliases(ctx, cluster, data)...)
+
+	diagnostics.Append(readKubeAPI(cluster, data)...)
+
+	return diagnostics
+}
+
+// readKubeAPI populates the Kubernetes API binding from the labels of the
+// server nodes, as k3d does not record it on the cluster itself. The host is
+// left as configured since k3d may substitute it, e.g. with the docker host,
+// and so is a port of 0 as the bound port is reported separately.
+func readKubeAPI(cluster *k3dtypes.Cluster, data *k3dClusterData) diag.Diagnostics {
+	var diagnostics diag.Diagnostics
+
+	var kubeAPI *k3dtypes.ExposureOpts
+	for _, node := range cluster.Nodes {
+		if node.Role == k3dtypes.ServerRole && node.ServerOpts.KubeAPI != nil {
+			kubeAPI = node.ServerOpts.KubeAPI
+			break
+		}
+	}
+
+	if kubeAPI == nil {
+		if data.K8sHostPort.IsUnknown() {
+			data.K8sHostPort = types.Int64Null()
+		}
+		if data.K8sBoundPort.IsUnknown() {
+			data.K8sBoundPort = types.Int64Null()
+		}
+		if data.K8sHostIP.IsUnknown() {
+			data.K8sHostIP = types.StringNull()
+		}
+		return diagnostics
+	}
+
+	// keep the brackets of a configured IPv6 address
+	if data.K8sHostIP.IsNull() || data.K8sHostIP.IsUnknown() || unbracketIP(data.K8sHostIP.ValueString()) != kubeAPI.Binding.HostIP {
+		data.K8sHostIP = types.StringValue(kubeAPI.Binding.HostIP)
+	}
+
+	port, err := strconv.ParseInt(kubeAPI.Binding.HostPort, 10, 32)
+	if err != nil {
+		diagnostics.Append(diag.NewWarningDiagnostic("Invalid port found in cluster settings", kubeAPI.Binding.HostPort))
+		if data.K8sHostPort.IsUnknown() {
+			data.K8sHostPort = types.Int64Null()
+		}
+		if data.K8sBoundPort.IsUnknown() {
+			data.K8sBoundPort = types.Int64Null()
+		}
+	} else {
+		data.K8sBoundPort = types.Int64Value(port)
+		if data.K8sHostPort.IsNull() || data.K8sHostPort.IsUnknown() || data.K8sHostPort.ValueInt64() != 0 {
 			data.K8sHostPort = types.Int64Value(port)
 		}
 	}
@@ -330,6 +977,80 @@ func (k3dCluster) readCluster(ctx context.Context, data *k3dClusterData) diag.Di
 	return diagnostics
 }
 
+// readLoadbalancer populates the loadbalancer attribute from the cluster. The
+// configuration overrides cannot be recovered from the running load balancer
+// so they are carried over from the existing data.
+func readLoadbalancer(ctx context.Context, cluster *k3dtypes.Cluster, data *k3dClusterData) diag.Diagnostics {
+	var diagnostics diag.Diagnostics
+
+	lb := k3dLoadbalancerData{
+		ConfigOverrides: types.ListNull(types.StringType),
+	}
+
+	if !data.Loadbalancer.IsNull() && !data.Loadbalancer.IsUnknown() {
+		diagnostics.Append(data.Loadbalancer.As(ctx, &lb, basetypes.ObjectAsOptions{})...)
+		if diagnostics.HasError() {
+			return diagnostics
+		}
+	}
+
+	portMap := make(map[string][]string)
+	enabled := cluster.ServerLoadBalancer != nil && cluster.ServerLoadBalancer.Node != nil
+	if enabled && cluster.ServerLoadBalancer.Config != nil {
+		for port, nodes := range cluster.ServerLoadBalancer.Config.Ports {
+			portMap[port] = nodes
+		}
+	}
+
+	lb.Enabled = types.BoolValue(enabled)
+
+	var diags diag.Diagnostics
+	lb.PortMap, diags = types.MapValueFrom(ctx, types.ListType{ElemType: types.StringType}, portMap)
+	diagnostics.Append(diags...)
+
+	data.Loadbalancer, diags = types.ObjectValueFrom(ctx, loadbalancerAttrTypes, lb)
+	diagnostics.Append(diags...)
+
+	return diagnostics
+}
+
+// readHostAliases populates the host aliases from the cluster's node labels,
+// which k3d uses to inject them into the nodes whenever the cluster starts.
+func readHostAliases(ctx context.Context, cluster *k3dtypes.Cluster, data *k3dClusterData) diag.Diagnostics {
+	var diagnostics diag.Diagnostics
+
+	startOpts, err := client.GetClusterStartOptsFromLabels(cluster)
+	if err != nil {
+		diagnostics.Append(diag.NewWarningDiagnostic("Error reading host aliases", err.Error()))
+		return diagnostics
+	}
+
+	if len(startOpts.HostAliases) == 0 {
+		// k3d records no aliases for an empty list, which has to be kept
+		if data.HostAliases.IsUnknown() || len(data.HostAliases.Elements()) > 0 {
+			data.HostAliases = types.ListNull(types.ObjectType{AttrTypes: hostAliasAttrTypes})
+		}
+		return diagnostics
+	}
+
+	aliases := make([]k3dHostAliasData, 0, len(startOpts.HostAliases))
+	for _, hostAlias := range startOpts.HostAliases {
+		hostnames, diags := types.ListValueFrom(ctx, types.StringType, hostAlias.Hostnames)
+		diagnostics.Append(diags...)
+
+		aliases = append(aliases, k3dHostAliasData{
+			IP:        types.StringValue(hostAlias.IP),
+			Hostnames: hostnames,
+		})
+	}
+
+	var diags diag.Diagnostics
+	data.HostAliases, diags = types.ListValueFrom(ctx, types.ObjectType{AttrTypes: hostAliasAttrTypes}, aliases)
+	diagnostics.Append(diags...)
+
+	return diagnostics
+}
+
 func (c k3dCluster) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
 	var data k3dClusterData
 
@@ -340,6 +1061,14 @@ func (c k3dCluster) Read(ctx context.Context, req resource.ReadRequest, resp *re
 		return
 	}
 
+	ctx = tflog.SetField(ctx, "cluster", data.Name.ValueString())
+
+	// the cluster has to be created again if it is gone
+	if _, err := c.runtime.ClusterGet(ctx, data.Name.ValueString()); errors.Is(err, client.ClusterGetNoNodesFoundError) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
 	resp.Diagnostics.Append(c.readCluster(ctx, &data)...)
 	if resp.Diagnostics.HasError() {
 		return
@@ -350,8 +1079,46 @@ func (c k3dCluster) Read(ctx context.Context, req resource.ReadRequest, resp *re
 }
 
 func (r k3dCluster) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	resp.Diagnostics.Append(diag.NewErrorDiagnostic("Updates are unsupported", ""))
-	return
+	var plan, state k3dClusterData
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = tflog.SetField(ctx, "cluster", plan.Name.ValueString())
+
+	// All attributes other than the image and the upgrade strategy require
+	// replacement so the image is the only thing that can need upgrading.
+	if !plan.Image.Equal(state.Image) {
+		if plan.Upgrade.ValueString() != upgradeStrategyRolling {
+			resp.Diagnostics.AddAttributeError(path.Root("upgrade_strategy"), "Updates are unsupported", "Changing the image requires upgrade_strategy to be rolling")
+			return
+		}
+
+		image, err := resolveK3sImage(plan.Image.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("image"), "Error resolving K3s image", err.Error())
+			return
+		}
+
+		tflog.Info(ctx, fmt.Sprintf("performing rolling upgrade of cluster %s to %s", plan.Name.ValueString(), image))
+		if err := rollingUpgrade(ctx, r.runtime, plan.Name.ValueString(), image); err != nil {
+			resp.Diagnostics.Append(clusterErrorDiagnostic("Error upgrading cluster", err, &plan, failedNodeLogs(ctx, r.runtime, err)...))
+			return
+		}
+		tflog.Info(ctx, "cluster successfully upgraded")
+	}
+
+	resp.Diagnostics.Append(r.readCluster(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags := resp.State.Set(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
 }
 
 func (r k3dCluster) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
@@ -364,8 +1131,10 @@ func (r k3dCluster) Delete(ctx context.Context, req resource.DeleteRequest, resp
 		return
 	}
 
+	ctx = tflog.SetField(ctx, "cluster", data.Name.ValueString())
+
 	tflog.Trace(ctx, "reading cluster info")
-	cluster, err := client.ClusterGet(ctx, runtimes.SelectedRuntime, &k3dtypes.Cluster{Name: data.Name.ValueString()})
+	cluster, err := r.runtime.ClusterGet(ctx, data.Name.ValueString())
 	if err != nil {
 		if errors.Is(err, client.ClusterGetNoNodesFoundError) {
 			return
@@ -375,26 +1144,33 @@ func (r k3dCluster) Delete(ctx context.Context, req resource.DeleteRequest, resp
 		return
 	}
 
-	tflog.Trace(ctx, "deleting the cluster")
-	if err := client.ClusterDelete(ctx, runtimes.SelectedRuntime, cluster, k3dtypes.ClusterDeleteOpts{SkipRegistryCheck: false}); err != nil {
-		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Failed to delete the cluster", err.Error()))
+	// k3d only removes networks it considers to be owned by the cluster.
+	// Make sure it leaves alone networks that existed before the cluster.
+	external := cluster.Network.External
+	if !data.NetworkCreated.IsNull() && !data.NetworkCreated.ValueBool() {
+		cluster.Network.External = true
 	}
 
-	tflog.Trace(ctx, "removing kubecfongig from default config")
-	if err := client.KubeconfigRemoveClusterFromDefaultConfig(ctx, cluster); err != nil {
-		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Failed to remove kubeconfig from default config", err.Error()))
+	tflog.Trace(ctx, "deleting the cluster")
+	if err := r.runtime.ClusterDelete(ctx, cluster, k3dtypes.ClusterDeleteOpts{SkipRegistryCheck: false}); err != nil {
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Failed to delete the cluster", err.Error()))
 	}
 
-	tflog.Trace(ctx, "removing standalone kubeconfig")
-	configDir, err := k3dutil.GetConfigDirOrCreate()
-	if err != nil {
-		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Failed to delete kubeconfig file", err.Error()))
-	} else {
-		kubeconfigfile := path.Join(configDir, fmt.Sprintf("kubeconfig-%s.yaml", cluster.Name))
-		if err := os.Remove(kubeconfigfile); err != nil {
-			if !os.IsNotExist(err) {
-				resp.Diagnostics.Append(diag.NewErrorDiagnostic(fmt.Sprintf("Failed to delete kubeconfig file '%s'", kubeconfigfile), err.Error()))
+	// Networks that were named in the configuration are external to k3d even
+	// when they were created along with the cluster, so remove those here.
+	if data.NetworkCreated.ValueBool() && external {
+		tflog.Trace(ctx, "deleting the cluster network")
+		if err := r.runtime.NetworkDelete(ctx, cluster.Network.Name); err != nil {
+			if errors.Is(err, runtimeerrors.ErrRuntimeNetworkNotEmpty) {
+				resp.Diagnostics.Append(diag.NewWarningDiagnostic(fmt.Sprintf("Network '%s' is still in use and was not deleted", cluster.Network.Name), err.Error()))
+			} else {
+				resp.Diagnostics.Append(diag.NewErrorDiagnostic(fmt.Sprintf("Failed to delete network '%s'", cluster.Network.Name), err.Error()))
 			}
 		}
 	}
+
+	tflog.Trace(ctx, "removing kubeconfig")
+	if err := r.runtime.KubeconfigRemove(ctx, cluster); err != nil {
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Failed to remove kubeconfig", err.Error()))
+	}
 }
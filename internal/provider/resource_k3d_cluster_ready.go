@@ -0,0 +1,220 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/k3d-io/k3d/v5/pkg/client"
+	"github.com/k3d-io/k3d/v5/pkg/runtimes"
+	k3dtypes "github.com/k3d-io/k3d/v5/pkg/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ resource.Resource = k3dClusterReady{}
+
+func NewClusterReadyResource() resource.Resource {
+	return k3dClusterReady{}
+}
+
+type k3dClusterReadyData struct {
+	ID           types.String `tfsdk:"id"`
+	ClusterName  types.String `tfsdk:"cluster_name"`
+	Timeout      types.String `tfsdk:"timeout"`
+	WaitForNodes types.Bool   `tfsdk:"wait_for_nodes"`
+	Deployments  types.List   `tfsdk:"deployments"`
+	Triggers     types.Map    `tfsdk:"triggers"`
+	ReadyNodes   types.List   `tfsdk:"ready_nodes"`
+}
+
+type k3dClusterReady struct {
+}
+
+func (k3dClusterReady) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_cluster_ready"
+}
+
+func (k3dClusterReady) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	defaultDeployments := make([]attr.Value, 0, len(defaultReadyDeployments))
+	for _, name := range defaultReadyDeployments {
+		defaultDeployments = append(defaultDeployments, types.StringValue(name))
+	}
+
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Waits for a K3D cluster to become ready to run workloads. Resources that depend on this resource are only created once all nodes of the cluster are `Ready` and the deployments in `kube-system` are `Available`.",
+
+		Attributes: map[string]schema.Attribute{
+			"cluster_name": schema.StringAttribute{
+				MarkdownDescription: "Name of the cluster to wait for",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"timeout": schema.StringAttribute{
+				MarkdownDescription: "How long to wait for the cluster to become ready. Defaults to `5m`",
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString("5m"),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					validateDuration,
+				},
+			},
+			"wait_for_nodes": schema.BoolAttribute{
+				MarkdownDescription: "Whether to wait for all nodes to be `Ready`. Defaults to `true`",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.RequiresReplace(),
+				},
+			},
+			"deployments": schema.ListAttribute{
+				MarkdownDescription: "Names of the deployments in `kube-system` to wait for to be `Available`. Defaults to `coredns` and `local-path-provisioner`",
+				Optional:            true,
+				Computed:            true,
+				ElementType:         types.StringType,
+				Default:             listdefault.StaticValue(types.ListValueMust(types.StringType, defaultDeployments)),
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+			},
+			"triggers": schema.MapAttribute{
+				MarkdownDescription: "Arbitrary values that cause the cluster to be waited for again when changed, e.g. the ID of a cluster that is replaced",
+				Optional:            true,
+				ElementType:         types.StringType,
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"ready_nodes": schema.ListAttribute{
+				MarkdownDescription: "Names of the Kubernetes nodes that were `Ready`",
+				Computed:            true,
+				ElementType:         types.StringType,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"id": schema.StringAttribute{
+				MarkdownDescription: "Name of the cluster",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (k3dClusterReady) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data k3dClusterReadyData
+
+	diags := req.Plan.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = tflog.SetField(ctx, "cluster", data.ClusterName.ValueString())
+
+	checks := readinessChecks{
+		Nodes:     data.WaitForNodes.ValueBool(),
+		Namespace: "kube-system",
+	}
+	resp.Diagnostics.Append(data.Deployments.ElementsAs(ctx, &checks.Deployments, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, err := time.ParseDuration(data.Timeout.ValueString())
+	if err != nil {
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Invalid timeout", err.Error()))
+		return
+	}
+
+	cluster, err := client.ClusterGet(ctx, runtimes.SelectedRuntime, &k3dtypes.Cluster{Name: data.ClusterName.ValueString()})
+	if err != nil {
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error reading k3d cluster", err.Error()))
+		return
+	}
+
+	clientset, err := kubernetesClient(ctx, cluster)
+	if err != nil {
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error creating Kubernetes client", err.Error()))
+		return
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	tflog.Info(ctx, fmt.Sprintf("waiting up to %s for cluster %s to become ready", timeout, cluster.Name))
+	ready, err := waitForClusterReady(waitCtx, clientset, checks, readinessPollInterval)
+	if err != nil {
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic(fmt.Sprintf("Cluster %s did not become ready", cluster.Name), err.Error()))
+		return
+	}
+
+	data.ReadyNodes, diags = types.ListValueFrom(ctx, types.StringType, ready)
+	resp.Diagnostics.Append(diags...)
+	data.ID = data.ClusterName
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (k3dClusterReady) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data k3dClusterReadyData
+
+	diags := req.State.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = tflog.SetField(ctx, "cluster", data.ClusterName.ValueString())
+
+	// the cluster has to be waited for again if it is gone
+	_, err := client.ClusterGet(ctx, runtimes.SelectedRuntime, &k3dtypes.Cluster{Name: data.ClusterName.ValueString()})
+	if err != nil {
+		if errors.Is(err, client.ClusterGetNoNodesFoundError) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error reading k3d cluster", err.Error()))
+		return
+	}
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (k3dClusterReady) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(diag.NewErrorDiagnostic("Updates are unsupported", ""))
+}
+
+func (k3dClusterReady) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	// Nothing to undo, the resource is removed from the state.
+}
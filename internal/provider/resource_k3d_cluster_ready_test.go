@@ -0,0 +1,43 @@
+package provider
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccK3DClusterReadyResource(t *testing.T) {
+	name := testAccRandomName("ready")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccK3DClusterReadyResourceConfig(name, testAccRandomPort()),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_cluster_ready.test", "id", name),
+					resource.TestCheckResourceAttr("k3d_cluster_ready.test", "timeout", "5m"),
+					resource.TestCheckResourceAttr("k3d_cluster_ready.test", "deployments.#", "2"),
+					resource.TestCheckResourceAttr("k3d_cluster_ready.test", "ready_nodes.#", "2"),
+					resource.TestCheckResourceAttr("k3d_cluster_ready.test", "ready_nodes.0", "k3d-"+name+"-agent-0"),
+				),
+			},
+		},
+	})
+}
+
+func testAccK3DClusterReadyResourceConfig(name string, port int) string {
+	return fmt.Sprintf(`
+resource "k3d_cluster" "test" {
+  name              = %[1]q
+  agents            = 1
+  k8s_api_host_port = %[2]d
+}
+
+resource "k3d_cluster_ready" "test" {
+  cluster_name = k3d_cluster.test.name
+}
+`, name, port)
+}
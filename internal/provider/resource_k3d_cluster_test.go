@@ -1,25 +1,633 @@
 package provider
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"net/http"
+	"regexp"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	client "github.com/k3d-io/k3d/v5/pkg/client"
+	"github.com/k3d-io/k3d/v5/pkg/runtimes"
+	k3dtypes "github.com/k3d-io/k3d/v5/pkg/types"
 )
 
+func init() {
+	resource.AddTestSweepers("k3d_cluster", &resource.Sweeper{
+		Name: "k3d_cluster",
+		F:    testSweepClusters,
+	})
+
+	resource.AddTestSweepers("k3d_registry", &resource.Sweeper{
+		Name:         "k3d_registry",
+		Dependencies: []string{"k3d_cluster"},
+		F:            testSweepRegistries,
+	})
+}
+
+// testSweepClusters deletes the clusters left behind by acceptance tests,
+// along with their networks and kubeconfigs.
+func testSweepClusters(_ string) error {
+	ctx := context.Background()
+	rt := k3dClient{}
+
+	clusters, err := client.ClusterList(ctx, runtimes.SelectedRuntime)
+	if err != nil {
+		return fmt.Errorf("failed to list clusters: %w", err)
+	}
+
+	var errs []error
+	for _, cluster := range clusters {
+		if !testSweepName(cluster.Name) {
+			continue
+		}
+
+		log.Printf("[INFO] Deleting cluster %s", cluster.Name)
+		if err := rt.ClusterDelete(ctx, cluster, k3dtypes.ClusterDeleteOpts{SkipRegistryCheck: false}); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete cluster %s: %w", cluster.Name, err))
+			continue
+		}
+
+		if err := rt.KubeconfigRemove(ctx, cluster); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// testSweepRegistries deletes the registries left behind by acceptance tests
+// that were not deleted along with their cluster.
+func testSweepRegistries(_ string) error {
+	ctx := context.Background()
+
+	nodes, err := k3dClient{}.NodesByLabel(ctx, map[string]string{k3dtypes.LabelRole: string(k3dtypes.RegistryRole)})
+	if err != nil {
+		return fmt.Errorf("failed to list registries: %w", err)
+	}
+
+	var errs []error
+	for _, node := range nodes {
+		if !testSweepName(node.Name) {
+			continue
+		}
+
+		log.Printf("[INFO] Deleting registry %s", node.Name)
+		if err := runtimes.SelectedRuntime.DeleteNode(ctx, node); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete registry %s: %w", node.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+var testFileAttrTypes = map[string]attr.Type{
+	"content":      types.StringType,
+	"source":       types.StringType,
+	"destination":  types.StringType,
+	"node_filters": types.ListType{ElemType: types.StringType},
+	"content_hash": types.StringType,
+}
+
+// testClusterData returns the planned data of a cluster with the schema
+// defaults applied.
+func testClusterData(name string) k3dClusterData {
+	return k3dClusterData{
+		ID:             types.StringUnknown(),
+		Name:           types.StringValue(name),
+		Servers:        types.Int64Value(1),
+		Agents:         types.Int64Value(0),
+		K8sHost:        types.StringNull(),
+		K8sHostIP:      types.StringValue("127.0.0.1"),
+		K8sHostPort:    types.Int64Value(6550),
+		K8sBoundPort:   types.Int64Unknown(),
+		Image:          types.StringUnknown(),
+		K3sVersion:     types.StringNull(),
+		Upgrade:        types.StringValue(upgradeStrategyRecreate),
+		ImageSHA:       types.StringUnknown(),
+		Network:        types.StringUnknown(),
+		Subnet:         types.StringUnknown(),
+		ClusterCIDRs:   types.ListNull(types.StringType),
+		ServiceCIDRs:   types.ListNull(types.StringType),
+		NetworkCreated: types.BoolUnknown(),
+		Loadbalancer:   types.ObjectUnknown(loadbalancerAttrTypes),
+		HostAliases:    types.ListNull(types.ObjectType{AttrTypes: hostAliasAttrTypes}),
+		Token:          types.StringUnknown(),
+		Files:          types.ListNull(types.ObjectType{AttrTypes: testFileAttrTypes}),
+		KeepOnFailure:  types.BoolValue(false),
+	}
+}
+
+func testFiles(t *testing.T, nodeFilters ...string) types.List {
+	t.Helper()
+
+	filters, diags := types.ListValueFrom(context.Background(), types.StringType, nodeFilters)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	file := types.ObjectValueMust(testFileAttrTypes, map[string]attr.Value{
+		"content":      types.StringValue("test"),
+		"source":       types.StringNull(),
+		"destination":  types.StringValue("/tmp/test"),
+		"node_filters": filters,
+		"content_hash": types.StringUnknown(),
+	})
+
+	return types.ListValueMust(types.ObjectType{AttrTypes: testFileAttrTypes}, []attr.Value{file})
+}
+
+func TestBuildClusterConfig(t *testing.T) {
+	data := testClusterData("test")
+	data.Agents = types.Int64Value(2)
+	data.K8sHostPort = types.Int64Unknown()
+	data.Files = testFiles(t, "agent:*")
+
+	clusterConfig, diags := buildClusterConfig(context.Background(), newFakeRuntime(), &data)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	roles := make(map[k3dtypes.Role]int)
+	for _, node := range clusterConfig.Cluster.Nodes {
+		roles[node.Role]++
+	}
+
+	if roles[k3dtypes.ServerRole] != 1 || roles[k3dtypes.AgentRole] != 2 {
+		t.Errorf("expected 1 server and 2 agents, got %v", roles)
+	}
+
+	// the port of a random binding is only picked on creation
+	if port := clusterConfig.Cluster.KubeAPI.Binding.HostPort; port != "" {
+		t.Errorf("expected no API port, got %q", port)
+	}
+}
+
+func TestBuildClusterConfig_channel(t *testing.T) {
+	denyNetwork(t)
+
+	data := testClusterData("test")
+	data.Agents = types.Int64Value(1)
+	data.Image = types.StringValue("latest")
+
+	// the fake runtime resolves release channels without the channel server
+	clusterConfig, diags := buildClusterConfig(context.Background(), newFakeRuntime(), &data)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	for _, node := range clusterConfig.Cluster.Nodes {
+		if node.Role != k3dtypes.LoadBalancerRole && node.Image != k3sDefaultImage() {
+			t.Errorf("expected node %s to run %s, got %s", node.Name, k3sDefaultImage(), node.Image)
+		}
+	}
+}
+
+func TestBuildClusterConfigInvalid(t *testing.T) {
+	cases := map[string]struct {
+		modify func(data *k3dClusterData)
+		path   path.Path
+	}{
+		"name": {
+			modify: func(data *k3dClusterData) {
+				data.Name = types.StringValue("not_a_hostname")
+			},
+			path: path.Root("name"),
+		},
+		"node filters": {
+			modify: func(data *k3dClusterData) {
+				data.Files = testFiles(t, "agent:0")
+			},
+			path: path.Root("file").AtListIndex(0).AtName("node_filters"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			data := testClusterData("test")
+			tc.modify(&data)
+
+			_, diags := buildClusterConfig(context.Background(), newFakeRuntime(), &data)
+			if !diags.HasError() {
+				t.Fatal("expected an error")
+			}
+
+			if len(tc.path.Steps()) == 0 {
+				return
+			}
+
+			for _, d := range diags.Errors() {
+				if withPath, ok := d.(diag.DiagnosticWithPath); ok && withPath.Path().Equal(tc.path) {
+					return
+				}
+			}
+			t.Errorf("expected an error at %s, got %v", tc.path, diags)
+		})
+	}
+}
+
+// denyNetwork fails the test if anything is requested over HTTP with the
+// default transport, which k3d uses to resolve K3s release channels.
+func denyNetwork(t *testing.T) {
+	t.Helper()
+
+	transport := http.DefaultTransport
+	http.DefaultTransport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		t.Errorf("unexpected request to %s", req.URL)
+		return nil, errors.New("network access is not allowed in this test")
+	})
+	t.Cleanup(func() { http.DefaultTransport = transport })
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// testModifyPlan runs ModifyPlan of the cluster resource. The state is null
+// if prior is nil.
+func testModifyPlan(t *testing.T, rt k3dRuntime, config, plan k3dClusterData, prior *k3dClusterData, requiresReplace ...path.Path) diag.Diagnostics {
+	t.Helper()
+	ctx := context.Background()
+
+	c := &k3dCluster{runtime: rt}
+	var schemaResp fwresource.SchemaResponse
+	c.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	set := func(data *k3dClusterData) tftypes.Value {
+		out := tfsdk.Plan{Schema: schemaResp.Schema}
+		if diags := out.Set(ctx, data); diags.HasError() {
+			t.Fatalf("unexpected diagnostics: %v", diags)
+		}
+		return out.Raw
+	}
+
+	req := fwresource.ModifyPlanRequest{
+		Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: set(&config)},
+		Plan:   tfsdk.Plan{Schema: schemaResp.Schema, Raw: set(&plan)},
+		State:  tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil)},
+	}
+	if prior != nil {
+		req.State.Raw = set(prior)
+	}
+
+	resp := fwresource.ModifyPlanResponse{Plan: req.Plan, RequiresReplace: requiresReplace}
+	c.ModifyPlan(ctx, req, &resp)
+
+	return resp.Diagnostics
+}
+
+func TestModifyPlan(t *testing.T) {
+	denyNetwork(t)
+
+	// the configuration is fully known, a placeholder is used for the
+	// computed values that are null in a real configuration
+	config := testClusterData("test")
+	config.ID = types.StringValue("test")
+	config.K8sBoundPort = types.Int64Value(6550)
+	config.Image = types.StringValue("latest")
+	config.ImageSHA = types.StringValue("sha256:test")
+	config.Network = types.StringValue("k3d-test")
+	config.Subnet = types.StringValue("172.28.0.0/16")
+	config.NetworkCreated = types.BoolValue(true)
+	config.Loadbalancer = types.ObjectNull(loadbalancerAttrTypes)
+	config.Token = types.StringValue("token")
+
+	// the release channel of the default image is not resolved
+	plan := testClusterData("test")
+	plan.Image = types.StringValue("latest")
+	if diags := testModifyPlan(t, newFakeRuntime(), config, plan, nil); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	// the configuration of an existing cluster is not validated again unless
+	// the cluster is replaced
+	file := types.ObjectValueMust(testFileAttrTypes, map[string]attr.Value{
+		"content":      types.StringValue("test"),
+		"source":       types.StringNull(),
+		"destination":  types.StringValue("/tmp/test"),
+		"node_filters": types.ListValueMust(types.StringType, []attr.Value{types.StringValue("agent:0")}),
+		"content_hash": types.StringValue("test"),
+	})
+	config.Files = types.ListValueMust(types.ObjectType{AttrTypes: testFileAttrTypes}, []attr.Value{file})
+	if diags := testModifyPlan(t, newFakeRuntime(), config, config, &config); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	if diags := testModifyPlan(t, newFakeRuntime(), config, config, &config, path.Root("file")); !diags.HasError() {
+		t.Fatal("expected the configuration of a replaced cluster to be validated")
+	}
+}
+
+func TestReadHostAliases(t *testing.T) {
+	ctx := context.Background()
+	aliasType := types.ObjectType{AttrTypes: hostAliasAttrTypes}
+	alias := types.ObjectValueMust(hostAliasAttrTypes, map[string]attr.Value{
+		"ip":        types.StringValue("10.10.10.10"),
+		"hostnames": types.ListValueMust(types.StringType, []attr.Value{types.StringValue("api.dev.internal")}),
+	})
+
+	cases := map[string]struct {
+		prior    types.List
+		expected types.List
+	}{
+		"null": {
+			prior:    types.ListNull(aliasType),
+			expected: types.ListNull(aliasType),
+		},
+		"empty": {
+			prior:    types.ListValueMust(aliasType, []attr.Value{}),
+			expected: types.ListValueMust(aliasType, []attr.Value{}),
+		},
+		"removed": {
+			prior:    types.ListValueMust(aliasType, []attr.Value{alias}),
+			expected: types.ListNull(aliasType),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			data := k3dClusterData{HostAliases: tc.prior}
+
+			diags := readHostAliases(ctx, &k3dtypes.Cluster{Name: "test"}, &data)
+			if diags.HasError() {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+
+			if !data.HostAliases.Equal(tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, data.HostAliases)
+			}
+		})
+	}
+}
+
+func TestK3DClusterResource(t *testing.T) {
+	rt := newFakeRuntime()
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:                 func() { testUnitPreCheck(t) },
+		ProtoV6ProviderFactories: testUnitProtoV6ProviderFactories(rt),
+		CheckDestroy:             testCheckFakeRuntimeEmpty(rt),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccK3DClusterResourceConfigRolling("unit-test", 6554, "v1.28.7+k3s1"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_cluster.test", "id", "unit-test"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "servers", "1"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "agents", "1"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "network", "k3d-unit-test"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "network_created", "true"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "subnet", "172.18.0.0/16"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "token", "fake-token"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "k8s_api_host_ip", "127.0.0.1"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "k8s_api_host_port", "6554"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "k8s_api_bound_port", "6554"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "image_sha", fakeImageID("rancher/k3s:v1.28.7-k3s1")),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "loadbalancer.enabled", "true"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "loadbalancer.port_map.6443.tcp.0", "k3d-unit-test-server-0"),
+				),
+			},
+			{
+				Config: testAccK3DClusterResourceConfigRolling("unit-test", 6554, "v1.29.2+k3s1"),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("k3d_cluster.test", plancheck.ResourceActionUpdate),
+					},
+				},
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_cluster.test", "k3s_version", "v1.29.2+k3s1"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "image_sha", fakeImageID("rancher/k3s:v1.29.2-k3s1")),
+					testCheckFakeClusterImages(rt, "unit-test", "docker.io/rancher/k3s:v1.29.2-k3s1"),
+				),
+			},
+		},
+	})
+}
+
+func TestK3DClusterResource_drift(t *testing.T) {
+	rt := newFakeRuntime()
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:                 func() { testUnitPreCheck(t) },
+		ProtoV6ProviderFactories: testUnitProtoV6ProviderFactories(rt),
+		CheckDestroy:             testCheckFakeRuntimeEmpty(rt),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccK3DClusterResourceConfigRolling("unit-test-drift", 6554, "v1.28.7+k3s1"),
+			},
+			// a deleted cluster is created again
+			{
+				PreConfig: func() {
+					rt.removeCluster("unit-test-drift")
+				},
+				Config: testAccK3DClusterResourceConfigRolling("unit-test-drift", 6554, "v1.28.7+k3s1"),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("k3d_cluster.test", plancheck.ResourceActionCreate),
+					},
+				},
+				Check: testCheckFakeClusterNodes(rt, "unit-test-drift", 3),
+			},
+			// a cluster that lost a node is replaced
+			{
+				PreConfig: func() {
+					rt.removeNode("k3d-unit-test-drift-agent-0")
+				},
+				Config: testAccK3DClusterResourceConfigRolling("unit-test-drift", 6554, "v1.28.7+k3s1"),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("k3d_cluster.test", plancheck.ResourceActionDestroyBeforeCreate),
+					},
+				},
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_cluster.test", "agents", "1"),
+					testCheckFakeClusterNodes(rt, "unit-test-drift", 3),
+				),
+			},
+		},
+	})
+}
+
+func TestK3DClusterResource_randomPort(t *testing.T) {
+	rt := newFakeRuntime()
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:                 func() { testUnitPreCheck(t) },
+		ProtoV6ProviderFactories: testUnitProtoV6ProviderFactories(rt),
+		CheckDestroy:             testCheckFakeRuntimeEmpty(rt),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccK3DClusterResourceConfigRandomPort("unit-test"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_cluster.first", "k8s_api_host_port", "0"),
+					resource.TestCheckResourceAttr("k3d_cluster.first", "k8s_api_bound_port", "40000"),
+					resource.TestCheckResourceAttr("k3d_cluster.second", "k8s_api_host_port", "0"),
+					resource.TestCheckResourceAttr("k3d_cluster.second", "k8s_api_bound_port", "40001"),
+				),
+			},
+			{
+				Config: testAccK3DClusterResourceConfigRandomPort("unit-test"),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectEmptyPlan(),
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestK3DClusterResource_createError(t *testing.T) {
+	rt := newFakeRuntime()
+	rt.failOn("ClusterRun", errors.New("failed to start node 'k3d-unit-test-error-server-0': Bind for 127.0.0.1:6553 failed: port is already allocated"))
+	rt.setLogs("k3d-unit-test-error-server-0", "starting k3s")
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:                 func() { testUnitPreCheck(t) },
+		ProtoV6ProviderFactories: testUnitProtoV6ProviderFactories(rt),
+		// the failed cluster is rolled back
+		CheckDestroy: testCheckFakeRuntimeEmpty(rt),
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccK3DClusterResourceConfigK3sVersion("unit-test-error", 6553, "v1.28.7+k3s1"),
+				ExpectError: regexp.MustCompile(`(?s)Error creating cluster.*starting\s+k3s`),
+			},
+		},
+	})
+}
+
+func TestK3DClusterResource_keepOnFailure(t *testing.T) {
+	rt := newFakeRuntime()
+	rt.failOn("ClusterRun", errors.New("Node k3d-unit-test-keep-server-0 failed to get ready: error waiting for log line"))
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:                 func() { testUnitPreCheck(t) },
+		ProtoV6ProviderFactories: testUnitProtoV6ProviderFactories(rt),
+		CheckDestroy:             testCheckFakeRuntimeEmpty(rt),
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccK3DClusterResourceConfigKeepOnFailure("unit-test-keep", 6568),
+				ExpectError: regexp.MustCompile(`Error creating cluster`),
+			},
+			// the kept cluster is tainted and replaced by the next apply
+			{
+				PreConfig: func() {
+					if rt.cluster("unit-test-keep") == nil {
+						t.Error("expected the failed cluster to be kept")
+					}
+					rt.failOn("ClusterRun", nil)
+				},
+				Config: testAccK3DClusterResourceConfigKeepOnFailure("unit-test-keep", 6568),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("k3d_cluster.test", plancheck.ResourceActionDestroyBeforeCreate),
+					},
+				},
+				Check: testCheckFakeClusterNodes(rt, "unit-test-keep", 2),
+			},
+		},
+	})
+}
+
+func TestK3DClusterResource_portConflict(t *testing.T) {
+	rt := newFakeRuntime()
+
+	other := testClusterData("unit-test-other")
+	other.K8sHostPort = types.Int64Value(6553)
+	rt.addCluster(t, other)
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:                 func() { testUnitPreCheck(t) },
+		ProtoV6ProviderFactories: testUnitProtoV6ProviderFactories(rt),
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccK3DClusterResourceConfigK3sVersion("unit-test-conflict", 6553, "v1.28.7+k3s1"),
+				ExpectError: regexp.MustCompile(`Port already in use`),
+			},
+		},
+	})
+}
+
+func testCheckFakeRuntimeEmpty(rt *fakeRuntime) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		return rt.checkEmpty()
+	}
+}
+
+func testCheckFakeClusterNodes(rt *fakeRuntime, name string, count int) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		cluster := rt.cluster(name)
+		if cluster == nil {
+			return fmt.Errorf("cluster %q not found", name)
+		}
+
+		if len(cluster.Nodes) != count {
+			return fmt.Errorf("expected cluster %q to have %d nodes, got %d", name, count, len(cluster.Nodes))
+		}
+
+		return nil
+	}
+}
+
+func testCheckFakeClusterImages(rt *fakeRuntime, name string, image string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		cluster := rt.cluster(name)
+		if cluster == nil {
+			return fmt.Errorf("cluster %q not found", name)
+		}
+
+		for _, node := range cluster.Nodes {
+			if node.Role == k3dtypes.LoadBalancerRole {
+				continue
+			}
+
+			reference, err := rt.NodeImage(context.Background(), node)
+			if err != nil {
+				return err
+			}
+
+			if !sameImage(reference, image) || node.Image != fakeImageID(image) {
+				return fmt.Errorf("expected node %s to run image %s, got %s (%s)", node.Name, image, reference, node.Image)
+			}
+		}
+
+		return nil
+	}
+}
+
 func TestAccK3DClusterResource(t *testing.T) {
+	name := testAccRandomName("cluster")
+
 	resource.Test(t, resource.TestCase{
 		PreCheck:                 func() { testAccPreCheck(t) },
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
 		Steps: []resource.TestStep{
 			// Create and Read testing
 			{
-				Config: testAccK3DClusterResourceConfig("acc-test"),
+				Config: testAccK3DClusterResourceConfig(name, testAccRandomPort()),
 				Check: resource.ComposeAggregateTestCheckFunc(
-					resource.TestCheckResourceAttr("k3d_cluster.test", "name", "acc-test"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "name", name),
 					resource.TestCheckResourceAttr("k3d_cluster.test", "servers", "1"),
 					resource.TestCheckResourceAttr("k3d_cluster.test", "agents", "0"),
-					resource.TestCheckResourceAttr("k3d_cluster.test", "network", "k3d-acc-test"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "network", "k3d-"+name),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "network_created", "true"),
+					resource.TestCheckResourceAttrSet("k3d_cluster.test", "subnet"),
+					resource.TestCheckResourceAttrSet("k3d_cluster.test", "token"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "loadbalancer.enabled", "true"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "loadbalancer.port_map.6443.tcp.0", "k3d-"+name+"-server-0"),
 				),
 			},
 			// Delete testing automatically occurs in TestCase
@@ -27,10 +635,508 @@ func TestAccK3DClusterResource(t *testing.T) {
 	})
 }
 
-func testAccK3DClusterResourceConfig(name string) string {
+func TestAccK3DClusterResource_subnet(t *testing.T) {
+	name := testAccRandomName("subnet")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccK3DClusterResourceConfigSubnet(name, testAccRandomPort(), "172.28.0.0/16"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_cluster.test", "subnet", "172.28.0.0/16"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "network", name+"-net"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "network_created", "true"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccK3DClusterResource_noLoadbalancer(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccK3DClusterResourceConfigNoLoadbalancer(testAccRandomName("nolb"), testAccRandomPort()),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_cluster.test", "loadbalancer.enabled", "false"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "loadbalancer.port_map.%", "0"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccK3DClusterResource_hostAliases(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccK3DClusterResourceConfigHostAliases(testAccRandomName("aliases"), testAccRandomPort()),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_cluster.test", "host_aliases.#", "1"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "host_aliases.0.ip", "10.10.10.10"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "host_aliases.0.hostnames.0", "api.dev.internal"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "host_aliases.0.hostnames.1", "db.dev.internal"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccK3DClusterResource_token(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccK3DClusterResourceConfigToken(testAccRandomName("token"), testAccRandomPort(), "acc-test-secret-token"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_cluster.test", "token", "acc-test-secret-token"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccK3DClusterResource_apiHost(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccK3DClusterResourceConfigAPIHost(testAccRandomName("api-host"), testAccRandomPort(), "k3d.localhost"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_cluster.test", "k8s_api_host", "k3d.localhost"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccK3DClusterResource_randomPort(t *testing.T) {
+	name := testAccRandomName("random-port")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccK3DClusterResourceConfigRandomPort(name),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_cluster.first", "k8s_api_host_port", "0"),
+					resource.TestCheckResourceAttr("k3d_cluster.second", "k8s_api_host_port", "0"),
+					resource.TestCheckResourceAttrWith("k3d_cluster.first", "k8s_api_bound_port", testCheckNonZeroPort),
+					resource.TestCheckResourceAttrWith("k3d_cluster.second", "k8s_api_bound_port", testCheckNonZeroPort),
+					resource.TestCheckResourceAttrPair("k3d_cluster.first", "k8s_api_host_ip", "k3d_cluster.second", "k8s_api_host_ip"),
+				),
+			},
+			// the picked ports are kept
+			{
+				Config: testAccK3DClusterResourceConfigRandomPort(name),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectEmptyPlan(),
+					},
+				},
+			},
+		},
+	})
+}
+
+func testCheckNonZeroPort(value string) error {
+	if value == "" || value == "0" {
+		return fmt.Errorf("expected a bound port, got %q", value)
+	}
+
+	return nil
+}
+
+func TestAccK3DClusterResource_portConflict(t *testing.T) {
+	name := testAccRandomName("port")
+	port := testAccRandomPort()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccK3DClusterResourceConfigPortConflict(name, port, false),
+			},
+			// the conflict is reported when planning the second cluster
+			{
+				Config:      testAccK3DClusterResourceConfigPortConflict(name, port, true),
+				ExpectError: regexp.MustCompile(regexp.QuoteMeta(fmt.Sprintf("already bound by node k3d-%s-1-serverlb", name))),
+			},
+		},
+	})
+}
+
+func TestAccK3DClusterResource_invalidConfig(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccK3DClusterResourceConfigInvalidFile(testAccRandomName("invalid")),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`Error injecting file`),
+			},
+		},
+	})
+}
+
+func TestAccK3DClusterResource_keepOnFailure(t *testing.T) {
+	name := testAccRandomName("keep")
+	port := testAccRandomPort()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// K3s fails to parse its config file, which shows in the logs
+			{
+				Config:      testAccK3DClusterResourceConfigKeepOnFailure(name, port),
+				ExpectError: regexp.MustCompile(`(?s)Error creating cluster.*Last\s+\d+\s+log\s+lines\s+of\s+node\s+k3d-` + name + `-server-0.*yaml:`),
+			},
+			// the kept cluster is tainted and replaced by the next apply
+			{
+				PreConfig: func() {
+					nodes, err := k3dClient{}.NodesByLabel(context.Background(), map[string]string{k3dtypes.LabelClusterName: name})
+					if err != nil {
+						t.Fatalf("failed to list the nodes of the kept cluster: %v", err)
+					}
+					if len(client.NodeFilterByRoles(nodes, []k3dtypes.Role{k3dtypes.ServerRole}, nil)) == 0 {
+						t.Error("expected the server node of the failed cluster to be kept")
+					}
+				},
+				Config: testAccK3DClusterResourceConfig(name, port),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("k3d_cluster.test", plancheck.ResourceActionDestroyBeforeCreate),
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestAccK3DClusterResource_files(t *testing.T) {
+	name := testAccRandomName("files")
+	port := testAccRandomPort()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccK3DClusterResourceConfigFiles(name, port, "acc-test"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_cluster.test", "file.#", "1"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "file.0.destination", "/var/lib/rancher/k3s/server/manifests/acc-test.yaml"),
+					resource.TestCheckResourceAttrSet("k3d_cluster.test", "file.0.content_hash"),
+				),
+			},
+			{
+				Config: testAccK3DClusterResourceConfigFiles(name, port, "acc-test-changed"),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("k3d_cluster.test", plancheck.ResourceActionDestroyBeforeCreate),
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestAccK3DClusterResource_k3sVersion(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccK3DClusterResourceConfigK3sVersion(testAccRandomName("version"), testAccRandomPort(), "v1.28.7+k3s1"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_cluster.test", "k3s_version", "v1.28.7+k3s1"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "image", "docker.io/rancher/k3s:v1.28.7-k3s1"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccK3DClusterResource_image(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccK3DClusterResourceConfigImage(testAccRandomName("image"), testAccRandomPort(), "rancher/k3s:v1.28.7-k3s1"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_cluster.test", "image", "rancher/k3s:v1.28.7-k3s1"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "k3s_version", "v1.28.7+k3s1"),
+					resource.TestMatchResourceAttr("k3d_cluster.test", "image_sha", regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)),
+					testAccCheckK3DClusterNodeImage("k3d_cluster.test", "rancher/k3s:v1.28.7-k3s1"),
+				),
+			},
+		},
+	})
+}
+
+// testAccCheckK3DClusterNodeImage checks that the runtime reports the image
+// reference of the server and agent nodes rather than the image ID.
+func testAccCheckK3DClusterNodeImage(resourceName string, image string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[resourceName]
+		if !ok {
+			return fmt.Errorf("resource %s not found", resourceName)
+		}
+
+		ctx := context.Background()
+		rt := k3dClient{}
+
+		cluster, err := rt.ClusterGet(ctx, rs.Primary.Attributes["name"])
+		if err != nil {
+			return err
+		}
+
+		for _, node := range client.NodeFilterByRoles(cluster.Nodes, []k3dtypes.Role{k3dtypes.ServerRole, k3dtypes.AgentRole}, nil) {
+			got, err := rt.NodeImage(ctx, node)
+			if err != nil {
+				return err
+			}
+
+			if got != image {
+				return fmt.Errorf("expected node %s to run image %q, got %q", node.Name, image, got)
+			}
+		}
+
+		return nil
+	}
+}
+
+func TestAccK3DClusterResource_rollingUpgrade(t *testing.T) {
+	name := testAccRandomName("rolling")
+	port := testAccRandomPort()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccK3DClusterResourceConfigRolling(name, port, "v1.27.11+k3s1"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_cluster.test", "k3s_version", "v1.27.11+k3s1"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "upgrade_strategy", "rolling"),
+				),
+			},
+			{
+				Config: testAccK3DClusterResourceConfigRolling(name, port, "v1.28.7+k3s1"),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("k3d_cluster.test", plancheck.ResourceActionUpdate),
+					},
+				},
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_cluster.test", "k3s_version", "v1.28.7+k3s1"),
+					resource.TestMatchResourceAttr("k3d_cluster.test", "image_sha", regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)),
+					testAccCheckK3DClusterNodeImage("k3d_cluster.test", "docker.io/rancher/k3s:v1.28.7-k3s1"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "servers", "1"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "agents", "1"),
+				),
+			},
+		},
+	})
+}
+
+func testAccK3DClusterResourceConfig(name string, port int) string {
+	return fmt.Sprintf(`
+resource "k3d_cluster" "test" {
+  name              = %[1]q
+  k8s_api_host_port = %[2]d
+}
+`, name, port)
+}
+
+func testAccK3DClusterResourceConfigK3sVersion(name string, port int, version string) string {
+	return fmt.Sprintf(`
+resource "k3d_cluster" "test" {
+  name              = %[1]q
+  k3s_version       = %[3]q
+  k8s_api_host_port = %[2]d
+}
+`, name, port, version)
+}
+
+func testAccK3DClusterResourceConfigImage(name string, port int, image string) string {
+	return fmt.Sprintf(`
+resource "k3d_cluster" "test" {
+  name              = %[1]q
+  image             = %[3]q
+  k8s_api_host_port = %[2]d
+}
+`, name, port, image)
+}
+
+func testAccK3DClusterResourceConfigRolling(name string, port int, version string) string {
+	return fmt.Sprintf(`
+resource "k3d_cluster" "test" {
+  name              = %[1]q
+  agents            = 1
+  k3s_version       = %[3]q
+  upgrade_strategy  = "rolling"
+  k8s_api_host_port = %[2]d
+}
+`, name, port, version)
+}
+
+func testAccK3DClusterResourceConfigSubnet(name string, port int, subnet string) string {
+	return fmt.Sprintf(`
+resource "k3d_cluster" "test" {
+  name              = %[1]q
+  network           = "%[1]s-net"
+  subnet            = %[3]q
+  k8s_api_host_port = %[2]d
+}
+`, name, port, subnet)
+}
+
+func testAccK3DClusterResourceConfigNoLoadbalancer(name string, port int) string {
+	return fmt.Sprintf(`
+resource "k3d_cluster" "test" {
+  name              = %[1]q
+  k8s_api_host_port = %[2]d
+
+  loadbalancer = {
+    enabled = false
+  }
+}
+`, name, port)
+}
+
+func testAccK3DClusterResourceConfigHostAliases(name string, port int) string {
+	return fmt.Sprintf(`
+resource "k3d_cluster" "test" {
+  name              = %[1]q
+  k8s_api_host_port = %[2]d
+
+  host_aliases = [
+    {
+      ip        = "10.10.10.10"
+      hostnames = ["api.dev.internal", "db.dev.internal"]
+    },
+  ]
+}
+`, name, port)
+}
+
+func testAccK3DClusterResourceConfigToken(name string, port int, token string) string {
+	return fmt.Sprintf(`
+resource "k3d_cluster" "test" {
+  name              = %[1]q
+  token             = %[3]q
+  k8s_api_host_port = %[2]d
+}
+`, name, port, token)
+}
+
+func testAccK3DClusterResourceConfigAPIHost(name string, port int, host string) string {
+	return fmt.Sprintf(`
+resource "k3d_cluster" "test" {
+  name              = %[1]q
+  k8s_api_host      = %[3]q
+  k8s_api_host_port = %[2]d
+}
+`, name, port, host)
+}
+
+func testAccK3DClusterResourceConfigRandomPort(name string) string {
+	return fmt.Sprintf(`
+resource "k3d_cluster" "first" {
+  name              = "%[1]s-1"
+  k3s_version       = "v1.28.7+k3s1"
+  k8s_api_host_port = 0
+}
+
+resource "k3d_cluster" "second" {
+  name              = "%[1]s-2"
+  k3s_version       = "v1.28.7+k3s1"
+  k8s_api_host_port = 0
+}
+`, name)
+}
+
+func testAccK3DClusterResourceConfigPortConflict(name string, port int, conflict bool) string {
+	config := fmt.Sprintf(`
+resource "k3d_cluster" "first" {
+  name              = "%[1]s-1"
+  k8s_api_host_port = %[2]d
+}
+`, name, port)
+
+	if conflict {
+		config += fmt.Sprintf(`
+resource "k3d_cluster" "second" {
+  name              = "%[1]s-2"
+  k8s_api_host_port = %[2]d
+}
+`, name, port)
+	}
+
+	return config
+}
+
+func testAccK3DClusterResourceConfigInvalidFile(name string) string {
 	return fmt.Sprintf(`
 resource "k3d_cluster" "test" {
   name = %[1]q
+
+  file {
+    content      = "test"
+    destination  = "/tmp/test"
+    node_filters = ["agent:0"]
+  }
 }
 `, name)
 }
+
+func testAccK3DClusterResourceConfigKeepOnFailure(name string, port int) string {
+	return fmt.Sprintf(`
+resource "k3d_cluster" "test" {
+  name              = %[1]q
+  k3s_version       = "v1.28.7+k3s1"
+  keep_on_failure   = true
+  k8s_api_host_port = %[2]d
+
+  # K3s refuses to start with an invalid config file
+  file {
+    destination = "/etc/rancher/k3s/config.yaml"
+    content     = "node-label: [invalid"
+  }
+}
+`, name, port)
+}
+
+func testAccK3DClusterResourceConfigFiles(name string, port int, namespace string) string {
+	return fmt.Sprintf(`
+resource "k3d_cluster" "test" {
+  name              = %[1]q
+  k8s_api_host_port = %[2]d
+
+  file {
+    destination  = "/var/lib/rancher/k3s/server/manifests/acc-test.yaml"
+    node_filters = ["server:0"]
+    content      = <<-EOT
+      apiVersion: v1
+      kind: Namespace
+      metadata:
+        name: %[3]s
+    EOT
+  }
+}
+`, name, port, namespace)
+}
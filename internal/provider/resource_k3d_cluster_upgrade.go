@@ -0,0 +1,185 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	client "github.com/k3d-io/k3d/v5/pkg/client"
+	k3dtypes "github.com/k3d-io/k3d/v5/pkg/types"
+)
+
+const (
+	upgradeStrategyRecreate = "recreate"
+	upgradeStrategyRolling  = "rolling"
+
+	// nodeReadyTimeout bounds how long a rolling upgrade waits for a
+	// replaced node to report Ready before giving up.
+	nodeReadyTimeout = 5 * time.Minute
+	// nodeReadyInterval is the time between two readiness checks.
+	nodeReadyInterval = 5 * time.Second
+)
+
+// rollingUpgrade replaces the server nodes of the cluster one at a time,
+// followed by the agent nodes, with nodes running the given image. Each
+// replaced node must become Ready before the next one is touched.
+func rollingUpgrade(ctx context.Context, rt k3dRuntime, clusterName string, image string) error {
+	cluster, err := rt.ClusterGet(ctx, clusterName)
+	if err != nil {
+		return fmt.Errorf("failed to read cluster %q: %w", clusterName, err)
+	}
+
+	servers := client.NodeFilterByRoles(cluster.Nodes, []k3dtypes.Role{k3dtypes.ServerRole}, nil)
+	agents := client.NodeFilterByRoles(cluster.Nodes, []k3dtypes.Role{k3dtypes.AgentRole}, nil)
+	sortNodesByName(servers)
+	sortNodesByName(agents)
+
+	if len(servers) == 0 {
+		return fmt.Errorf("cluster %q has no server nodes", clusterName)
+	}
+
+	// kubectl is run within a server node to check readiness. Once a server
+	// has been replaced its replacement is used instead.
+	readyServer := servers[0]
+
+	for _, node := range append(servers, agents...) {
+		nodeCtx := tflog.SetField(ctx, "node", node.Name)
+
+		// k3d reports the image ID on the node, so compare the reference
+		// the node was created from instead
+		current, err := rt.NodeImage(nodeCtx, node)
+		if err != nil {
+			return fmt.Errorf("failed to read image of node %s: %w", node.Name, err)
+		}
+		if sameImage(current, image) {
+			tflog.Debug(nodeCtx, fmt.Sprintf("node %s is already running image %s", node.Name, image))
+			continue
+		}
+
+		name := node.Name
+
+		// the Kubernetes node outlives the replaced container, so remember
+		// its last heartbeat to tell a stale Ready condition apart
+		_, heartbeat, err := nodeReadyCondition(nodeCtx, rt, readyServer, name)
+		if err != nil {
+			tflog.Warn(nodeCtx, fmt.Sprintf("failed to read the Ready condition of node %s: %s", name, err))
+		}
+
+		tflog.Info(nodeCtx, fmt.Sprintf("upgrading node %s to image %s", name, image))
+		replacement, err := upgradeNode(nodeCtx, rt, node, image)
+		if err != nil {
+			return err
+		}
+
+		if replacement.Role == k3dtypes.ServerRole {
+			readyServer = replacement
+		}
+
+		tflog.Debug(nodeCtx, fmt.Sprintf("waiting for node %s to become ready", name))
+		if err := waitForNodeReady(nodeCtx, rt, readyServer, name, heartbeat); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// upgradeNode replaces a single node with a copy of itself running the given
+// image and returns the replacement. The data volumes of the existing node
+// are carried over so that the K3s datastore survives the replacement.
+func upgradeNode(ctx context.Context, rt k3dRuntime, node *k3dtypes.Node, image string) (*k3dtypes.Node, error) {
+	name := node.Name
+
+	replacement, err := client.CopyNode(ctx, node, client.CopyNodeOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy node %s: %w", name, err)
+	}
+
+	volumes, err := rt.NodeDataVolumes(ctx, node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine data volumes of node %s: %w", name, err)
+	}
+
+	replacement.Image = image
+	replacement.Volumes = append(replacement.Volumes, volumes...)
+
+	if err := rt.NodeReplace(ctx, node, replacement); err != nil {
+		return nil, fmt.Errorf("failed to replace node %s: %w", name, err)
+	}
+
+	return replacement, nil
+}
+
+// waitForNodeReady uses kubectl within the given server node to wait for the
+// named Kubernetes node to report the Ready condition with a heartbeat other
+// than the given one, i.e. one that was reported by the replaced kubelet.
+// Errors are retried until nodeReadyTimeout, as the API server is not
+// reachable while the server node itself is being replaced.
+func waitForNodeReady(ctx context.Context, rt k3dRuntime, server *k3dtypes.Node, name string, heartbeat string) error {
+	ctx, cancel := context.WithTimeout(ctx, nodeReadyTimeout)
+	defer cancel()
+
+	for {
+		status, current, err := nodeReadyCondition(ctx, rt, server, name)
+		switch {
+		case err != nil:
+			tflog.Debug(ctx, fmt.Sprintf("failed to read the Ready condition of node %s: %s", name, err))
+		case status == "True" && current != heartbeat:
+			return nil
+		default:
+			err = fmt.Errorf("the Ready condition is %q with heartbeat %q", status, current)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("node %s did not become ready within %s: %w", name, nodeReadyTimeout, err)
+		case <-time.After(nodeReadyInterval):
+		}
+	}
+}
+
+// nodeReadyCondition returns the status and the last heartbeat of the Ready
+// condition of the named Kubernetes node.
+func nodeReadyCondition(ctx context.Context, rt k3dRuntime, server *k3dtypes.Node, name string) (string, string, error) {
+	cmd := []string{
+		"kubectl", "get", "node", name,
+		"--output", `jsonpath={range .status.conditions[?(@.type=="Ready")]}{.status} {.lastHeartbeatTime}{end}`,
+	}
+
+	output, err := rt.NodeExec(ctx, server, cmd)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %s", err, output)
+	}
+
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return "", "", fmt.Errorf("unexpected output: %s", output)
+	}
+
+	return fields[0], fields[1], nil
+}
+
+// sameImage reports whether two image references refer to the same image,
+// e.g. rancher/k3s:v1.29.2-k3s1 and docker.io/rancher/k3s:v1.29.2-k3s1.
+func sameImage(a string, b string) bool {
+	return normalizeImage(a) == normalizeImage(b)
+}
+
+func normalizeImage(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image
+	}
+
+	return reference.TagNameOnly(named).String()
+}
+
+func sortNodesByName(nodes []*k3dtypes.Node) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+}
@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestSameImage(t *testing.T) {
+	cases := []struct {
+		a, b string
+		same bool
+	}{
+		{"rancher/k3s:v1.29.2-k3s1", "docker.io/rancher/k3s:v1.29.2-k3s1", true},
+		{"docker.io/rancher/k3s", "rancher/k3s:latest", true},
+		{"rancher/k3s:v1.28.7-k3s1", "rancher/k3s:v1.29.2-k3s1", false},
+		{"registry.example.com/k3s:v1.29.2-k3s1", "rancher/k3s:v1.29.2-k3s1", false},
+	}
+
+	for _, tc := range cases {
+		if got := sameImage(tc.a, tc.b); got != tc.same {
+			t.Errorf("sameImage(%q, %q) = %t, expected %t", tc.a, tc.b, got, tc.same)
+		}
+	}
+}
+
+func TestRollingUpgrade(t *testing.T) {
+	ctx := context.Background()
+	rt := newFakeRuntime()
+
+	data := testClusterData("unit-test-rolling")
+	data.Agents = types.Int64Value(1)
+	data.Image = types.StringValue("rancher/k3s:v1.28.7-k3s1")
+	rt.addCluster(t, data)
+
+	// the same image with a different spelling must not replace the nodes
+	if err := rollingUpgrade(ctx, rt, "unit-test-rolling", k3sVersionToImage("v1.28.7+k3s1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rt.replacements) != 0 {
+		t.Fatalf("expected no nodes to be replaced, got %v", rt.replacements)
+	}
+
+	if err := rollingUpgrade(ctx, rt, "unit-test-rolling", k3sVersionToImage("v1.29.2+k3s1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"k3d-unit-test-rolling-server-0", "k3d-unit-test-rolling-agent-0"} {
+		if rt.replacements[name] != 1 {
+			t.Errorf("expected node %s to be replaced once, got %d", name, rt.replacements[name])
+		}
+	}
+}
+
+func TestWaitForNodeReady_staleCondition(t *testing.T) {
+	rt := newFakeRuntime()
+
+	data := testClusterData("unit-test-stale")
+	data.Image = types.StringValue("rancher/k3s:v1.28.7-k3s1")
+	rt.addCluster(t, data)
+
+	server := rt.cluster("unit-test-stale").Nodes[0]
+	_, heartbeat, err := nodeReadyCondition(context.Background(), rt, server, server.Name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the node has not been replaced, so its Ready condition is stale
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := waitForNodeReady(ctx, rt, server, server.Name, heartbeat); err == nil {
+		t.Fatal("expected a stale Ready condition not to be accepted")
+	}
+}
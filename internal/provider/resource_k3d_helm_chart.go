@@ -0,0 +1,329 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/k3d-io/k3d/v5/pkg/client"
+	"github.com/k3d-io/k3d/v5/pkg/runtimes"
+	runtimeerrors "github.com/k3d-io/k3d/v5/pkg/runtimes/errors"
+	k3dtypes "github.com/k3d-io/k3d/v5/pkg/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ resource.Resource = k3dHelmChart{}
+
+func NewHelmChartResource() resource.Resource {
+	return k3dHelmChart{}
+}
+
+type k3dHelmChartData struct {
+	ID              types.String `tfsdk:"id"`
+	ClusterName     types.String `tfsdk:"cluster_name"`
+	Name            types.String `tfsdk:"name"`
+	Chart           types.String `tfsdk:"chart"`
+	Repo            types.String `tfsdk:"repo"`
+	Version         types.String `tfsdk:"version"`
+	TargetNamespace types.String `tfsdk:"target_namespace"`
+	CreateNamespace types.Bool   `tfsdk:"create_namespace"`
+	Values          types.String `tfsdk:"values"`
+	Path            types.String `tfsdk:"path"`
+	Status          types.String `tfsdk:"status"`
+}
+
+type k3dHelmChart struct {
+}
+
+func (k3dHelmChart) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_helm_chart"
+}
+
+func (k3dHelmChart) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Helm chart installed by the K3s helm-controller. A `HelmChart` manifest is written into the manifests directory of every server node of the cluster.",
+
+		Attributes: map[string]schema.Attribute{
+			"cluster_name": schema.StringAttribute{
+				MarkdownDescription: "Name of the cluster to install the chart into",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"name": schema.StringAttribute{
+				MarkdownDescription: "Name of the `HelmChart` resource, which is also used as the Helm release name",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringvalidator.LengthBetween(1, 53),
+					stringvalidator.RegexMatches(
+						regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`),
+						"must consist of lower case alphanumeric characters or '-', and must start and end with an alphanumeric character",
+					),
+				},
+			},
+			"chart": schema.StringAttribute{
+				MarkdownDescription: "Name of the chart in the repository, or a URL to the chart archive",
+				Required:            true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
+			"repo": schema.StringAttribute{
+				MarkdownDescription: "URL of the chart repository",
+				Optional:            true,
+			},
+			"version": schema.StringAttribute{
+				MarkdownDescription: "Version of the chart to install. Defaults to the latest version.",
+				Optional:            true,
+			},
+			"target_namespace": schema.StringAttribute{
+				MarkdownDescription: "Namespace to install the release into. Defaults to `kube-system`.",
+				Optional:            true,
+			},
+			"create_namespace": schema.BoolAttribute{
+				MarkdownDescription: "Whether to create the target namespace if it does not exist",
+				Optional:            true,
+			},
+			"values": schema.StringAttribute{
+				MarkdownDescription: "Chart values in YAML format",
+				Optional:            true,
+			},
+			"path": schema.StringAttribute{
+				MarkdownDescription: "Path of the `HelmChart` manifest within the server nodes",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"status": schema.StringAttribute{
+				MarkdownDescription: "Install status of the chart as reported by the helm-controller install job. One of `pending`, `deployed` or `failed`.",
+				Computed:            true,
+			},
+			"id": schema.StringAttribute{
+				MarkdownDescription: "Identifier of the chart in the form `<cluster_name>/<name>`",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func helmChartManifestPath(name string) string {
+	return manifestPath(fmt.Sprintf("helm-chart-%s", name))
+}
+
+// writeHelmChart renders the HelmChart manifest and writes it to the server
+// nodes of the cluster.
+func (k3dHelmChart) writeHelmChart(ctx context.Context, servers []*k3dtypes.Node, data *k3dHelmChartData) diag.Diagnostics {
+	var diagnostics diag.Diagnostics
+
+	content, err := renderHelmChart(data.Name.ValueString(), helmChartSpec{
+		Chart:           data.Chart.ValueString(),
+		Repo:            data.Repo.ValueString(),
+		Version:         data.Version.ValueString(),
+		TargetNamespace: data.TargetNamespace.ValueString(),
+		CreateNamespace: data.CreateNamespace.ValueBool(),
+		ValuesContent:   data.Values.ValueString(),
+	})
+	if err != nil {
+		diagnostics.Append(diag.NewErrorDiagnostic("Error rendering HelmChart manifest", err.Error()))
+		return diagnostics
+	}
+
+	path := helmChartManifestPath(data.Name.ValueString())
+
+	tflog.Info(ctx, fmt.Sprintf("writing HelmChart manifest %s", path))
+	if err := writeFileToNodes(ctx, servers, content, path); err != nil {
+		diagnostics.Append(diag.NewErrorDiagnostic("Error writing HelmChart manifest", err.Error()))
+		return diagnostics
+	}
+
+	data.Path = types.StringValue(path)
+	data.Status = types.StringValue(helmChartStatus(ctx, servers[0], data.Name.ValueString()))
+
+	return diagnostics
+}
+
+func (h k3dHelmChart) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data k3dHelmChartData
+
+	diags := req.Plan.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = tflog.SetField(ctx, "cluster", data.ClusterName.ValueString())
+
+	servers, err := clusterServers(ctx, data.ClusterName.ValueString())
+	if err != nil {
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error reading k3d cluster", err.Error()))
+		return
+	}
+
+	resp.Diagnostics.Append(h.writeHelmChart(ctx, servers, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.ID = types.StringValue(fmt.Sprintf("%s/%s", data.ClusterName.ValueString(), data.Name.ValueString()))
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (k3dHelmChart) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data k3dHelmChartData
+
+	diags := req.State.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = tflog.SetField(ctx, "cluster", data.ClusterName.ValueString())
+
+	servers, err := clusterServers(ctx, data.ClusterName.ValueString())
+	if err != nil {
+		if errors.Is(err, client.ClusterGetNoNodesFoundError) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error reading k3d cluster", err.Error()))
+		return
+	}
+
+	path := helmChartManifestPath(data.Name.ValueString())
+
+	tflog.Info(ctx, fmt.Sprintf("reading HelmChart manifest %s", path))
+	content, err := readFileFromNode(ctx, servers[0], path)
+	if err != nil {
+		if errors.Is(err, runtimeerrors.ErrRuntimeFileNotFound) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error reading HelmChart manifest", err.Error()))
+		return
+	}
+
+	chart, err := parseHelmChart(content)
+	if err != nil {
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error reading HelmChart manifest", err.Error()))
+		return
+	}
+
+	data.Chart = types.StringValue(chart.Spec.Chart)
+	data.Repo = optionalString(data.Repo, chart.Spec.Repo)
+	data.Version = optionalString(data.Version, chart.Spec.Version)
+	data.TargetNamespace = optionalString(data.TargetNamespace, chart.Spec.TargetNamespace)
+	data.Values = optionalString(data.Values, chart.Spec.ValuesContent)
+	if chart.Spec.CreateNamespace || !data.CreateNamespace.IsNull() {
+		data.CreateNamespace = types.BoolValue(chart.Spec.CreateNamespace)
+	}
+	data.Path = types.StringValue(path)
+	data.Status = types.StringValue(helmChartStatus(ctx, servers[0], data.Name.ValueString()))
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (h k3dHelmChart) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data k3dHelmChartData
+
+	diags := req.Plan.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = tflog.SetField(ctx, "cluster", data.ClusterName.ValueString())
+
+	servers, err := clusterServers(ctx, data.ClusterName.ValueString())
+	if err != nil {
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error reading k3d cluster", err.Error()))
+		return
+	}
+
+	resp.Diagnostics.Append(h.writeHelmChart(ctx, servers, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (k3dHelmChart) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data k3dHelmChartData
+
+	diags := req.State.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = tflog.SetField(ctx, "cluster", data.ClusterName.ValueString())
+
+	servers, err := clusterServers(ctx, data.ClusterName.ValueString())
+	if err != nil {
+		// the chart is gone along with the cluster
+		if errors.Is(err, client.ClusterGetNoNodesFoundError) {
+			return
+		}
+
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error reading k3d cluster", err.Error()))
+		return
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("removing HelmChart manifest %s", data.Path.ValueString()))
+	if err := removeFileFromNodes(ctx, servers, data.Path.ValueString()); err != nil {
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error removing HelmChart manifest", err.Error()))
+		return
+	}
+
+	// deleting the HelmChart resource causes helm-controller to uninstall
+	// the release
+	tflog.Info(ctx, fmt.Sprintf("deleting HelmChart %s", data.Name.ValueString()))
+	cmd := []string{
+		"kubectl", "delete", "helmchart",
+		"--namespace", helmChartNamespace,
+		"--ignore-not-found",
+		data.Name.ValueString(),
+	}
+	if err := runtimes.SelectedRuntime.ExecInNode(ctx, servers[0], cmd); err != nil {
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error deleting HelmChart", err.Error()))
+	}
+}
+
+// optionalString returns the value read back from the runtime, keeping an
+// unset optional attribute null when the value is empty.
+func optionalString(current types.String, value string) types.String {
+	if value == "" && current.IsNull() {
+		return current
+	}
+
+	return types.StringValue(value)
+}
@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccK3DHelmChartResource(t *testing.T) {
+	name := testAccRandomName("helm")
+	port := testAccRandomPort()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccK3DHelmChartResourceConfig(name, port, 1),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_helm_chart.test", "id", name+"/podinfo"),
+					resource.TestCheckResourceAttr("k3d_helm_chart.test", "path", "/var/lib/rancher/k3s/server/manifests/helm-chart-podinfo.yaml"),
+					resource.TestCheckResourceAttr("k3d_helm_chart.test", "create_namespace", "true"),
+					resource.TestCheckResourceAttrSet("k3d_helm_chart.test", "status"),
+				),
+			},
+			// update the values in place
+			{
+				Config: testAccK3DHelmChartResourceConfig(name, port, 2),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_helm_chart.test", "values", "replicaCount: 2\n"),
+				),
+			},
+		},
+	})
+}
+
+func testAccK3DHelmChartResourceConfig(name string, port int, replicas int) string {
+	return fmt.Sprintf(`
+resource "k3d_cluster" "test" {
+  name              = %[1]q
+  k8s_api_host_port = %[2]d
+}
+
+resource "k3d_helm_chart" "test" {
+  cluster_name     = k3d_cluster.test.name
+  name             = "podinfo"
+  chart            = "podinfo"
+  repo             = "https://stefanprodan.github.io/podinfo"
+  target_namespace = "podinfo"
+  create_namespace = true
+  values           = "replicaCount: %[3]d\n"
+}
+`, name, port, replicas)
+}
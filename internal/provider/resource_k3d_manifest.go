@@ -0,0 +1,235 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/k3d-io/k3d/v5/pkg/client"
+	runtimeerrors "github.com/k3d-io/k3d/v5/pkg/runtimes/errors"
+)
+
+// k3sManifestsDir is the directory watched by the K3s deploy controller. Any
+// manifest placed there is applied to the cluster automatically.
+const k3sManifestsDir = "/var/lib/rancher/k3s/server/manifests"
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ resource.Resource = k3dManifest{}
+
+func NewManifestResource() resource.Resource {
+	return k3dManifest{}
+}
+
+type k3dManifestData struct {
+	ID          types.String `tfsdk:"id"`
+	ClusterName types.String `tfsdk:"cluster_name"`
+	Name        types.String `tfsdk:"name"`
+	Content     types.String `tfsdk:"content"`
+	Path        types.String `tfsdk:"path"`
+}
+
+type k3dManifest struct {
+}
+
+func (k3dManifest) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_manifest"
+}
+
+func (k3dManifest) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Kubernetes manifest that is automatically applied by the K3s deploy controller. The manifest is written into the manifests directory of every server node of the cluster.",
+
+		Attributes: map[string]schema.Attribute{
+			"cluster_name": schema.StringAttribute{
+				MarkdownDescription: "Name of the cluster to deploy the manifest to",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"name": schema.StringAttribute{
+				MarkdownDescription: "Name of the manifest. The manifest is stored as `<name>.yaml` in the K3s manifests directory.",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(
+						regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`),
+						"must only contain alphanumeric characters, '.', '_' or '-'",
+					),
+				},
+			},
+			"content": schema.StringAttribute{
+				MarkdownDescription: "YAML content of the manifest. Multiple documents may be separated with `---`.",
+				Required:            true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
+			"path": schema.StringAttribute{
+				MarkdownDescription: "Path of the manifest within the server nodes",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"id": schema.StringAttribute{
+				MarkdownDescription: "Identifier of the manifest in the form `<cluster_name>/<name>`",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func manifestPath(name string) string {
+	return fmt.Sprintf("%s/%s.yaml", k3sManifestsDir, name)
+}
+
+func (k3dManifest) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data k3dManifestData
+
+	diags := req.Plan.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = tflog.SetField(ctx, "cluster", data.ClusterName.ValueString())
+
+	servers, err := clusterServers(ctx, data.ClusterName.ValueString())
+	if err != nil {
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error reading k3d cluster", err.Error()))
+		return
+	}
+
+	path := manifestPath(data.Name.ValueString())
+
+	tflog.Info(ctx, fmt.Sprintf("writing manifest %s", path))
+	if err := writeFileToNodes(ctx, servers, []byte(data.Content.ValueString()), path); err != nil {
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error writing manifest", err.Error()))
+		return
+	}
+
+	data.ID = types.StringValue(fmt.Sprintf("%s/%s", data.ClusterName.ValueString(), data.Name.ValueString()))
+	data.Path = types.StringValue(path)
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (k3dManifest) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data k3dManifestData
+
+	diags := req.State.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = tflog.SetField(ctx, "cluster", data.ClusterName.ValueString())
+
+	servers, err := clusterServers(ctx, data.ClusterName.ValueString())
+	if err != nil {
+		if errors.Is(err, client.ClusterGetNoNodesFoundError) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error reading k3d cluster", err.Error()))
+		return
+	}
+
+	path := manifestPath(data.Name.ValueString())
+
+	tflog.Info(ctx, fmt.Sprintf("reading manifest %s", path))
+	content, err := readFileFromNode(ctx, servers[0], path)
+	if err != nil {
+		if errors.Is(err, runtimeerrors.ErrRuntimeFileNotFound) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error reading manifest", err.Error()))
+		return
+	}
+
+	data.Content = types.StringValue(string(content))
+	data.Path = types.StringValue(path)
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (k3dManifest) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data k3dManifestData
+
+	diags := req.Plan.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = tflog.SetField(ctx, "cluster", data.ClusterName.ValueString())
+
+	servers, err := clusterServers(ctx, data.ClusterName.ValueString())
+	if err != nil {
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error reading k3d cluster", err.Error()))
+		return
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("updating manifest %s", data.Path.ValueString()))
+	if err := writeFileToNodes(ctx, servers, []byte(data.Content.ValueString()), data.Path.ValueString()); err != nil {
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error writing manifest", err.Error()))
+		return
+	}
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (k3dManifest) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data k3dManifestData
+
+	diags := req.State.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = tflog.SetField(ctx, "cluster", data.ClusterName.ValueString())
+
+	servers, err := clusterServers(ctx, data.ClusterName.ValueString())
+	if err != nil {
+		// the manifest is gone along with the cluster
+		if errors.Is(err, client.ClusterGetNoNodesFoundError) {
+			return
+		}
+
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error reading k3d cluster", err.Error()))
+		return
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("removing manifest %s", data.Path.ValueString()))
+	if err := removeFileFromNodes(ctx, servers, data.Path.ValueString()); err != nil {
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error removing manifest", err.Error()))
+	}
+}
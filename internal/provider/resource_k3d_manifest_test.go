@@ -0,0 +1,54 @@
+package provider
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccK3DManifestResource(t *testing.T) {
+	name := testAccRandomName("manifest")
+	port := testAccRandomPort()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccK3DManifestResourceConfig(name, port, "acc-test"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_manifest.test", "id", name+"/acc-test-namespace"),
+					resource.TestCheckResourceAttr("k3d_manifest.test", "path", "/var/lib/rancher/k3s/server/manifests/acc-test-namespace.yaml"),
+				),
+			},
+			// update the manifest in place
+			{
+				Config: testAccK3DManifestResourceConfig(name, port, "acc-test-changed"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_manifest.test", "id", name+"/acc-test-namespace"),
+				),
+			},
+		},
+	})
+}
+
+func testAccK3DManifestResourceConfig(name string, port int, namespace string) string {
+	return fmt.Sprintf(`
+resource "k3d_cluster" "test" {
+  name              = %[1]q
+  k8s_api_host_port = %[2]d
+}
+
+resource "k3d_manifest" "test" {
+  cluster_name = k3d_cluster.test.name
+  name         = "acc-test-namespace"
+  content      = <<-EOT
+    apiVersion: v1
+    kind: Namespace
+    metadata:
+      name: %[3]s
+  EOT
+}
+`, name, port, namespace)
+}
@@ -0,0 +1,380 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	runtimeerrors "github.com/k3d-io/k3d/v5/pkg/runtimes/errors"
+	k3dtypes "github.com/k3d-io/k3d/v5/pkg/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ resource.Resource = &k3dNetwork{}
+var _ resource.ResourceWithConfigure = &k3dNetwork{}
+
+func NewNetworkResource() resource.Resource {
+	return &k3dNetwork{}
+}
+
+type k3dNetworkData struct {
+	ID          types.String `tfsdk:"id"`
+	Name        types.String `tfsdk:"name"`
+	Subnet      types.String `tfsdk:"subnet"`
+	Gateway     types.String `tfsdk:"gateway"`
+	IPRange     types.String `tfsdk:"ip_range"`
+	Labels      types.Map    `tfsdk:"labels"`
+	IPv6Subnet  types.String `tfsdk:"ipv6_subnet"`
+	IPv6Gateway types.String `tfsdk:"ipv6_gateway"`
+}
+
+type k3dNetwork struct {
+	runtime k3dRuntime
+}
+
+func (n *k3dNetwork) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// the provider is not configured yet during validation
+	if req.ProviderData == nil {
+		return
+	}
+
+	rt, ok := req.ProviderData.(k3dRuntime)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected a k3d runtime, got %T. This is always a bug in the provider code and should be reported to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	n.runtime = rt
+}
+
+func (k3dNetwork) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_network"
+}
+
+func (k3dNetwork) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Runtime network that can be shared by multiple K3D clusters and other containers",
+
+		Attributes: map[string]schema.Attribute{
+			"name": schema.StringAttribute{
+				MarkdownDescription: "Name of the network",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"subnet": schema.StringAttribute{
+				MarkdownDescription: "IPv4 subnet of the network in CIDR notation. If unset, the runtime picks a free subnet.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					validateIPv4CIDR,
+				},
+			},
+			"gateway": schema.StringAttribute{
+				MarkdownDescription: "IPv4 gateway address of the network. If unset, the runtime picks the gateway from the subnet.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					validateIPv4,
+				},
+			},
+			"ip_range": schema.StringAttribute{
+				MarkdownDescription: "Range of the IPv4 subnet, in CIDR notation, from which container addresses are allocated",
+				Optional:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					validateIPv4CIDR,
+				},
+			},
+			"ipv6_subnet": schema.StringAttribute{
+				MarkdownDescription: "IPv6 subnet of the network in CIDR notation (e.g. `fd00:28::/64`). Setting it makes the network dual-stack.",
+				Optional:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					validateIPv6CIDR,
+				},
+			},
+			"ipv6_gateway": schema.StringAttribute{
+				MarkdownDescription: "IPv6 gateway address of the network. If unset, the runtime picks the gateway from the IPv6 subnet.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{
+					validateIPv6,
+				},
+			},
+			"labels": schema.MapAttribute{
+				MarkdownDescription: "Runtime labels to attach to the network",
+				Optional:            true,
+				ElementType:         types.StringType,
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"id": schema.StringAttribute{
+				MarkdownDescription: "The runtime ID of the network",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (n k3dNetwork) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data k3dNetworkData
+
+	diags := req.Plan.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = tflog.SetField(ctx, "network", data.Name.ValueString())
+
+	_, err := n.runtime.NetworkGet(ctx, data.Name.ValueString())
+	if err == nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("name"),
+			"A network with the same name already exists",
+			fmt.Sprintf("Network %q already exists. Import it into the state or choose another name.", data.Name.ValueString()),
+		)
+		return
+	} else if !errors.Is(err, runtimeerrors.ErrRuntimeNetworkNotExists) {
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error reading network", err.Error()))
+		return
+	}
+
+	spec := networkSpec{
+		Name:   data.Name.ValueString(),
+		Labels: make(map[string]string),
+	}
+	resp.Diagnostics.Append(data.Labels.ElementsAs(ctx, &spec.Labels, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !data.Subnet.IsUnknown() && !data.Subnet.IsNull() {
+		spec.IPAM = append(spec.IPAM, networkIPAM{
+			Subnet:  data.Subnet.ValueString(),
+			Gateway: data.Gateway.ValueString(),
+			IPRange: data.IPRange.ValueString(),
+		})
+	} else if (!data.Gateway.IsUnknown() && !data.Gateway.IsNull()) || !data.IPRange.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("subnet"),
+			"Missing subnet",
+			"A subnet must be specified when setting the gateway or the IP range of the network",
+		)
+		return
+	}
+
+	if !data.IPv6Subnet.IsNull() {
+		if len(spec.IPAM) == 0 {
+			// docker needs an explicit IPv4 subnet next to the IPv6 one
+			resp.Diagnostics.AddAttributeError(
+				path.Root("subnet"),
+				"Missing subnet",
+				"An IPv4 subnet must be specified when setting the IPv6 subnet of the network",
+			)
+			return
+		}
+
+		spec.IPAM = append(spec.IPAM, networkIPAM{
+			Subnet:  data.IPv6Subnet.ValueString(),
+			Gateway: data.IPv6Gateway.ValueString(),
+		})
+	} else if !data.IPv6Gateway.IsUnknown() && !data.IPv6Gateway.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("ipv6_subnet"),
+			"Missing IPv6 subnet",
+			"An IPv6 subnet must be specified when setting the IPv6 gateway of the network",
+		)
+		return
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("creating network %s", data.Name.ValueString()))
+	id, err := n.runtime.NetworkCreate(ctx, spec)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "pool overlaps") {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("subnet"),
+				"Error creating network",
+				fmt.Sprintf("The subnet overlaps with an existing network. Pick another subnet.\n\n%s", err),
+			)
+			return
+		}
+
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error creating network", err.Error()))
+		return
+	}
+
+	data.ID = types.StringValue(id)
+
+	resp.Diagnostics.Append(n.readNetwork(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+// readNetwork refreshes the network data from the runtime. The ID is cleared
+// if the network no longer exists.
+func (n k3dNetwork) readNetwork(ctx context.Context, data *k3dNetworkData) diag.Diagnostics {
+	var diagnostics diag.Diagnostics
+
+	tflog.Info(ctx, fmt.Sprintf("reading network: %s", data.Name.ValueString()))
+	details, err := n.runtime.NetworkInspect(ctx, data.ID.ValueString())
+	if err != nil {
+		if errors.Is(err, runtimeerrors.ErrRuntimeNetworkNotExists) {
+			data.ID = types.StringNull()
+			return diagnostics
+		}
+
+		diagnostics.Append(diag.NewErrorDiagnostic("Error reading network", err.Error()))
+		return diagnostics
+	}
+
+	data.ID = types.StringValue(details.ID)
+	data.Name = types.StringValue(details.Name)
+
+	// dual-stack networks have one IPAM config per address family
+	for _, config := range details.IPAM {
+		prefix, err := netip.ParsePrefix(config.Subnet)
+		if err != nil {
+			continue
+		}
+
+		if prefix.Addr().Is6() {
+			data.IPv6Subnet = types.StringValue(config.Subnet)
+			if config.Gateway != "" {
+				data.IPv6Gateway = types.StringValue(config.Gateway)
+			}
+			continue
+		}
+
+		data.Subnet = types.StringValue(config.Subnet)
+		if config.Gateway != "" {
+			data.Gateway = types.StringValue(config.Gateway)
+		}
+		if config.IPRange != "" {
+			data.IPRange = types.StringValue(config.IPRange)
+		}
+	}
+
+	if data.Subnet.IsUnknown() {
+		data.Subnet = types.StringNull()
+	}
+	if data.Gateway.IsUnknown() {
+		data.Gateway = types.StringNull()
+	}
+	if data.IPv6Gateway.IsUnknown() {
+		data.IPv6Gateway = types.StringNull()
+	}
+
+	// hide the labels added to every k3d runtime object
+	labels := make(map[string]string)
+	for k, v := range details.Labels {
+		if _, ok := k3dtypes.DefaultRuntimeLabels[k]; ok {
+			continue
+		}
+		labels[k] = v
+	}
+
+	if len(labels) > 0 || !data.Labels.IsNull() {
+		value, diags := types.MapValueFrom(ctx, types.StringType, labels)
+		diagnostics.Append(diags...)
+		data.Labels = value
+	}
+
+	return diagnostics
+}
+
+func (n k3dNetwork) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data k3dNetworkData
+
+	diags := req.State.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = tflog.SetField(ctx, "network", data.Name.ValueString())
+
+	resp.Diagnostics.Append(n.readNetwork(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.ID.IsNull() {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (k3dNetwork) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(diag.NewErrorDiagnostic("Updates are unsupported", ""))
+}
+
+func (n k3dNetwork) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data k3dNetworkData
+
+	diags := req.State.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = tflog.SetField(ctx, "network", data.Name.ValueString())
+
+	tflog.Trace(ctx, "deleting the network")
+	if err := n.runtime.NetworkDelete(ctx, data.ID.ValueString()); err != nil {
+		if errors.Is(err, runtimeerrors.ErrRuntimeNetworkNotEmpty) {
+			resp.Diagnostics.Append(diag.NewErrorDiagnostic(fmt.Sprintf("Network '%s' is still in use", data.Name.ValueString()), "Remove all clusters and containers attached to the network before deleting it"))
+			return
+		}
+
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Failed to delete the network", err.Error()))
+	}
+}
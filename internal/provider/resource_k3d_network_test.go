@@ -0,0 +1,192 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"testing"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/k3d-io/k3d/v5/pkg/runtimes"
+	dockerruntime "github.com/k3d-io/k3d/v5/pkg/runtimes/docker"
+	k3dtypes "github.com/k3d-io/k3d/v5/pkg/types"
+)
+
+func init() {
+	resource.AddTestSweepers("k3d_network", &resource.Sweeper{
+		Name:         "k3d_network",
+		Dependencies: []string{"k3d_cluster", "k3d_registry"},
+		F:            testSweepNetworks,
+	})
+}
+
+// testSweepNetworks deletes the networks left behind by acceptance tests. The
+// networks are listed through the docker API, as the k3d runtime cannot list
+// networks.
+func testSweepNetworks(_ string) error {
+	ctx := context.Background()
+
+	if runtimes.SelectedRuntime.ID() != runtimes.Docker.ID() {
+		log.Printf("[WARN] Not sweeping networks with the %s runtime", runtimes.SelectedRuntime.ID())
+		return nil
+	}
+
+	docker, err := dockerruntime.GetDockerClient()
+	if err != nil {
+		return fmt.Errorf("failed to create docker client: %w", err)
+	}
+	defer docker.Close()
+
+	args := filters.NewArgs()
+	for k, v := range k3dtypes.DefaultRuntimeLabels {
+		args.Add("label", fmt.Sprintf("%s=%s", k, v))
+	}
+
+	networks, err := docker.NetworkList(ctx, dockertypes.NetworkListOptions{Filters: args})
+	if err != nil {
+		return fmt.Errorf("failed to list networks: %w", err)
+	}
+
+	var errs []error
+	for _, network := range networks {
+		if !testSweepName(network.Name) {
+			continue
+		}
+
+		log.Printf("[INFO] Deleting network %s", network.Name)
+		if err := (k3dClient{}).NetworkDelete(ctx, network.Name); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete network %s: %w", network.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func TestK3DNetworkResource(t *testing.T) {
+	rt := newFakeRuntime()
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:                 func() { testUnitPreCheck(t) },
+		ProtoV6ProviderFactories: testUnitProtoV6ProviderFactories(rt),
+		CheckDestroy:             testCheckFakeRuntimeEmpty(rt),
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitK3DNetworkResourceConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_network.test", "subnet", "172.29.0.0/16"),
+					resource.TestCheckResourceAttr("k3d_network.test", "gateway", "172.29.0.1"),
+					resource.TestCheckResourceAttr("k3d_network.test", "ipv6_subnet", "fd00:29::/64"),
+					resource.TestCheckResourceAttr("k3d_network.test", "ipv6_gateway", "fd00:29::1"),
+					resource.TestCheckResourceAttr("k3d_network.test", "labels.%", "1"),
+					resource.TestCheckResourceAttr("k3d_network.test", "labels.purpose", "unit-test"),
+					resource.TestCheckResourceAttrSet("k3d_network.test", "id"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "network", "unit-test"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "network_created", "false"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccK3DNetworkResource(t *testing.T) {
+	name := testAccRandomName("network")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccK3DNetworkResourceConfig(name, testAccRandomPort()),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_network.test", "name", name),
+					resource.TestCheckResourceAttr("k3d_network.test", "subnet", "172.29.0.0/16"),
+					resource.TestCheckResourceAttr("k3d_network.test", "gateway", "172.29.0.1"),
+					resource.TestCheckResourceAttr("k3d_network.test", "labels.purpose", "acc-test"),
+					resource.TestCheckResourceAttrSet("k3d_network.test", "id"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "network", name),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "network_created", "false"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccK3DNetworkResource_dualStack(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccK3DNetworkResourceConfigDualStack(testAccRandomName("dual-stack"), testAccRandomPort()),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_network.test", "subnet", "172.30.0.0/16"),
+					resource.TestCheckResourceAttr("k3d_network.test", "ipv6_subnet", "fd00:30::/64"),
+					resource.TestCheckResourceAttr("k3d_network.test", "ipv6_gateway", "fd00:30::1"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "cluster_cidrs.#", "2"),
+					resource.TestCheckResourceAttr("k3d_cluster.test", "service_cidrs.1", "fd00:43::/112"),
+				),
+			},
+		},
+	})
+}
+
+func testAccK3DNetworkResourceConfig(name string, port int) string {
+	return fmt.Sprintf(`
+resource "k3d_network" "test" {
+  name   = %[1]q
+  subnet = "172.29.0.0/16"
+
+  labels = {
+    purpose = "acc-test"
+  }
+}
+
+resource "k3d_cluster" "test" {
+  name              = %[1]q
+  network           = k3d_network.test.name
+  k8s_api_host_port = %[2]d
+}
+`, name, port)
+}
+
+func testAccK3DNetworkResourceConfigDualStack(name string, port int) string {
+	return fmt.Sprintf(`
+resource "k3d_network" "test" {
+  name         = %[1]q
+  subnet       = "172.30.0.0/16"
+  ipv6_subnet  = "fd00:30::/64"
+  ipv6_gateway = "fd00:30::1"
+}
+
+resource "k3d_cluster" "test" {
+  name              = %[1]q
+  network           = k3d_network.test.name
+  k8s_api_host_ip   = "[::1]"
+  k8s_api_host_port = %[2]d
+
+  cluster_cidrs = ["10.42.0.0/16", "fd00:42::/56"]
+  service_cidrs = ["10.43.0.0/16", "fd00:43::/112"]
+}
+`, name, port)
+}
+
+const testUnitK3DNetworkResourceConfig = `
+resource "k3d_network" "test" {
+  name        = "unit-test"
+  subnet      = "172.29.0.0/16"
+  ipv6_subnet = "fd00:29::/64"
+
+  labels = {
+    purpose = "unit-test"
+  }
+}
+
+resource "k3d_cluster" "test" {
+  name        = "unit-test"
+  network     = k3d_network.test.name
+  k3s_version = "v1.28.7+k3s1"
+}
+`
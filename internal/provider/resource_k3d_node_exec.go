@@ -0,0 +1,322 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/k3d-io/k3d/v5/pkg/client"
+	"github.com/k3d-io/k3d/v5/pkg/runtimes"
+	k3dtypes "github.com/k3d-io/k3d/v5/pkg/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ resource.Resource = k3dNodeExec{}
+
+func NewNodeExecResource() resource.Resource {
+	return k3dNodeExec{}
+}
+
+type k3dNodeExecData struct {
+	ID          types.String `tfsdk:"id"`
+	ClusterName types.String `tfsdk:"cluster_name"`
+	Command     types.List   `tfsdk:"command"`
+	Roles       types.List   `tfsdk:"roles"`
+	Nodes       types.List   `tfsdk:"nodes"`
+	Triggers    types.Map    `tfsdk:"triggers"`
+	FailOnError types.Bool   `tfsdk:"fail_on_error"`
+	Results     types.List   `tfsdk:"results"`
+}
+
+type k3dNodeExecResultData struct {
+	Node     types.String `tfsdk:"node"`
+	Stdout   types.String `tfsdk:"stdout"`
+	Stderr   types.String `tfsdk:"stderr"`
+	ExitCode types.Int64  `tfsdk:"exit_code"`
+}
+
+var nodeExecResultAttrTypes = map[string]attr.Type{
+	"node":      types.StringType,
+	"stdout":    types.StringType,
+	"stderr":    types.StringType,
+	"exit_code": types.Int64Type,
+}
+
+// defaultNodeExecRoles are the roles of the nodes a command is run on when
+// neither roles nor nodes are configured.
+var defaultNodeExecRoles = []string{string(k3dtypes.ServerRole), string(k3dtypes.AgentRole)}
+
+type k3dNodeExec struct {
+}
+
+func (k3dNodeExec) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_node_exec"
+}
+
+func (k3dNodeExec) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: "Runs a command once within nodes of a K3D cluster. The command is run again whenever any of the arguments other than `fail_on_error` change.",
+
+		Attributes: map[string]schema.Attribute{
+			"cluster_name": schema.StringAttribute{
+				MarkdownDescription: "Name of the cluster whose nodes the command is run in",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"command": schema.ListAttribute{
+				MarkdownDescription: "Command to run along with its arguments. The command is not run through a shell.",
+				Required:            true,
+				ElementType:         types.StringType,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+			},
+			"roles": schema.ListAttribute{
+				MarkdownDescription: "Roles of the nodes to run the command in (`server`, `agent` or `loadbalancer`). If neither `roles` nor `nodes` are set, the command is run in all server and agent nodes.",
+				Optional:            true,
+				ElementType:         types.StringType,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.List{
+					listvalidator.ValueStringsAre(
+						stringvalidator.OneOf(string(k3dtypes.ServerRole), string(k3dtypes.AgentRole), string(k3dtypes.LoadBalancerRole)),
+					),
+				},
+			},
+			"nodes": schema.ListAttribute{
+				MarkdownDescription: "Names of the nodes to run the command in. When combined with `roles`, only the named nodes with one of the roles are selected.",
+				Optional:            true,
+				ElementType:         types.StringType,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+			},
+			"triggers": schema.MapAttribute{
+				MarkdownDescription: "Arbitrary values that cause the command to be run again when changed",
+				Optional:            true,
+				ElementType:         types.StringType,
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"fail_on_error": schema.BoolAttribute{
+				MarkdownDescription: "Whether a non-zero exit code of the command fails the apply. Defaults to `true`",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
+			},
+			"results": schema.ListNestedAttribute{
+				MarkdownDescription: "Output of the command for each node it was run in, ordered by node name",
+				Computed:            true,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.UseStateForUnknown(),
+				},
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"node": schema.StringAttribute{
+							MarkdownDescription: "Name of the node",
+							Computed:            true,
+						},
+						"stdout": schema.StringAttribute{
+							MarkdownDescription: "Standard output of the command",
+							Computed:            true,
+						},
+						"stderr": schema.StringAttribute{
+							MarkdownDescription: "Standard error of the command",
+							Computed:            true,
+						},
+						"exit_code": schema.Int64Attribute{
+							MarkdownDescription: "Exit code of the command",
+							Computed:            true,
+						},
+					},
+				},
+			},
+			"id": schema.StringAttribute{
+				MarkdownDescription: "Unique identifier of the command run",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+// selectExecNodes returns the nodes matching the roles and names, sorted by
+// name. Empty roles or names match any node.
+func selectExecNodes(nodes []*k3dtypes.Node, roles []string, names []string) []*k3dtypes.Node {
+	if len(roles) == 0 && len(names) == 0 {
+		roles = defaultNodeExecRoles
+	}
+
+	var selected []*k3dtypes.Node
+	for _, node := range nodes {
+		if len(roles) > 0 && !containsString(roles, string(node.Role)) {
+			continue
+		}
+
+		if len(names) > 0 && !containsString(names, node.Name) {
+			continue
+		}
+
+		selected = append(selected, node)
+	}
+
+	sortNodesByName(selected)
+
+	return selected
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (k3dNodeExec) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data k3dNodeExecData
+
+	diags := req.Plan.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = tflog.SetField(ctx, "cluster", data.ClusterName.ValueString())
+
+	var command, roles, names []string
+	resp.Diagnostics.Append(data.Command.ElementsAs(ctx, &command, false)...)
+	resp.Diagnostics.Append(data.Roles.ElementsAs(ctx, &roles, false)...)
+	resp.Diagnostics.Append(data.Nodes.ElementsAs(ctx, &names, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	cluster, err := client.ClusterGet(ctx, runtimes.SelectedRuntime, &k3dtypes.Cluster{Name: data.ClusterName.ValueString()})
+	if err != nil {
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error reading k3d cluster", err.Error()))
+		return
+	}
+
+	nodes := selectExecNodes(cluster.Nodes, roles, names)
+	if len(nodes) == 0 {
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("No nodes selected", fmt.Sprintf("Cluster %q has no nodes matching the configured roles and nodes", cluster.Name)))
+		return
+	}
+
+	results := make([]k3dNodeExecResultData, 0, len(nodes))
+	for _, node := range nodes {
+		nodeCtx := tflog.SetField(ctx, "node", node.Name)
+		tflog.Info(nodeCtx, fmt.Sprintf("running %q in node %s", strings.Join(command, " "), node.Name))
+		result, err := execInNode(nodeCtx, node, command)
+		if err != nil {
+			resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error running command", err.Error()))
+			return
+		}
+
+		if result.ExitCode != 0 && data.FailOnError.ValueBool() {
+			resp.Diagnostics.Append(diag.NewErrorDiagnostic(
+				fmt.Sprintf("Command failed in node %s with exit code %d", node.Name, result.ExitCode),
+				result.Stderr,
+			))
+			return
+		}
+
+		results = append(results, k3dNodeExecResultData{
+			Node:     types.StringValue(node.Name),
+			Stdout:   types.StringValue(result.Stdout),
+			Stderr:   types.StringValue(result.Stderr),
+			ExitCode: types.Int64Value(int64(result.ExitCode)),
+		})
+	}
+
+	data.Results, diags = types.ListValueFrom(ctx, types.ObjectType{AttrTypes: nodeExecResultAttrTypes}, results)
+	resp.Diagnostics.Append(diags...)
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error generating ID", err.Error()))
+		return
+	}
+	data.ID = types.StringValue(id)
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (k3dNodeExec) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data k3dNodeExecData
+
+	diags := req.State.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = tflog.SetField(ctx, "cluster", data.ClusterName.ValueString())
+
+	// the command only needs to be run again if the cluster it ran in is gone
+	_, err := client.ClusterGet(ctx, runtimes.SelectedRuntime, &k3dtypes.Cluster{Name: data.ClusterName.ValueString()})
+	if err != nil {
+		if errors.Is(err, client.ClusterGetNoNodesFoundError) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+
+		resp.Diagnostics.Append(diag.NewErrorDiagnostic("Error reading k3d cluster", err.Error()))
+		return
+	}
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (k3dNodeExec) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data k3dNodeExecData
+
+	diags := req.Plan.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// only fail_on_error can be updated in place, which has no effect on a
+	// command that has already run
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (k3dNodeExec) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	// Nothing to undo, the resource is removed from the state.
+}
@@ -0,0 +1,114 @@
+package provider
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	k3dtypes "github.com/k3d-io/k3d/v5/pkg/types"
+)
+
+func TestSelectExecNodes(t *testing.T) {
+	nodes := []*k3dtypes.Node{
+		{Name: "k3d-test-serverlb", Role: k3dtypes.LoadBalancerRole},
+		{Name: "k3d-test-server-1", Role: k3dtypes.ServerRole},
+		{Name: "k3d-test-server-0", Role: k3dtypes.ServerRole},
+		{Name: "k3d-test-agent-0", Role: k3dtypes.AgentRole},
+	}
+
+	cases := map[string]struct {
+		roles    []string
+		names    []string
+		expected []string
+	}{
+		"default": {
+			expected: []string{"k3d-test-agent-0", "k3d-test-server-0", "k3d-test-server-1"},
+		},
+		"roles": {
+			roles:    []string{"loadbalancer", "agent"},
+			expected: []string{"k3d-test-agent-0", "k3d-test-serverlb"},
+		},
+		"names": {
+			names:    []string{"k3d-test-server-1", "k3d-test-serverlb"},
+			expected: []string{"k3d-test-server-1", "k3d-test-serverlb"},
+		},
+		"roles and names": {
+			roles:    []string{"server"},
+			names:    []string{"k3d-test-server-1", "k3d-test-serverlb"},
+			expected: []string{"k3d-test-server-1"},
+		},
+		"no match": {
+			names: []string{"k3d-other-server-0"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var actual []string
+			for _, node := range selectExecNodes(nodes, tc.roles, tc.names) {
+				actual = append(actual, node.Name)
+			}
+
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("selected %v, expected %v", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestAccK3DNodeExecResource(t *testing.T) {
+	name := testAccRandomName("exec")
+	port := testAccRandomPort()
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccK3DNodeExecResourceConfig(name, port, "1"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_node_exec.test", "results.#", "2"),
+					resource.TestCheckResourceAttr("k3d_node_exec.test", "results.0.node", "k3d-"+name+"-agent-0"),
+					resource.TestCheckResourceAttr("k3d_node_exec.test", "results.0.stdout", "hello\n"),
+					resource.TestCheckResourceAttr("k3d_node_exec.test", "results.0.exit_code", "0"),
+					resource.TestCheckResourceAttr("k3d_node_exec.failing", "results.0.exit_code", "3"),
+					resource.TestCheckResourceAttr("k3d_node_exec.failing", "results.0.stderr", "oops\n"),
+				),
+			},
+			// changing the triggers runs the command again
+			{
+				Config: testAccK3DNodeExecResourceConfig(name, port, "2"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("k3d_node_exec.test", "triggers.run", "2"),
+				),
+			},
+		},
+	})
+}
+
+func testAccK3DNodeExecResourceConfig(name string, port int, run string) string {
+	return fmt.Sprintf(`
+resource "k3d_cluster" "test" {
+  name              = %[1]q
+  agents            = 1
+  k8s_api_host_port = %[2]d
+}
+
+resource "k3d_node_exec" "test" {
+  cluster_name = k3d_cluster.test.name
+  command      = ["echo", "hello"]
+
+  triggers = {
+    run = %[3]q
+  }
+}
+
+resource "k3d_node_exec" "failing" {
+  cluster_name  = k3d_cluster.test.name
+  roles         = ["server"]
+  command       = ["sh", "-c", "echo oops >&2; exit 3"]
+  fail_on_error = false
+}
+`, name, port, run)
+}
@@ -0,0 +1,453 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	client "github.com/k3d-io/k3d/v5/pkg/client"
+	confutils "github.com/k3d-io/k3d/v5/pkg/config"
+	config "github.com/k3d-io/k3d/v5/pkg/config/v1alpha5"
+	"github.com/k3d-io/k3d/v5/pkg/runtimes"
+	dockerruntime "github.com/k3d-io/k3d/v5/pkg/runtimes/docker"
+	runtimeerrors "github.com/k3d-io/k3d/v5/pkg/runtimes/errors"
+	k3dtypes "github.com/k3d-io/k3d/v5/pkg/types"
+	k3dutil "github.com/k3d-io/k3d/v5/pkg/util"
+)
+
+// k3dRuntime is the subset of the k3d client and the container runtime that
+// the cluster resource and the nodes data source use. It is implemented by
+// k3dClient for the selected k3d runtime and can be replaced in tests.
+type k3dRuntime interface {
+	// ClusterGet returns client.ClusterGetNoNodesFoundError if the cluster
+	// does not exist.
+	ClusterGet(ctx context.Context, name string) (*k3dtypes.Cluster, error)
+	ClusterRun(ctx context.Context, clusterConfig *config.ClusterConfig) error
+	ClusterDelete(ctx context.Context, cluster *k3dtypes.Cluster, opts k3dtypes.ClusterDeleteOpts) error
+	// ClusterConfig transforms a simple configuration into a cluster
+	// configuration, resolving release channel images, and
+	// ClusterConfigValidate validates the result against the runtime.
+	ClusterConfig(ctx context.Context, simpleConf config.SimpleConfig) (*config.ClusterConfig, error)
+	ClusterConfigValidate(ctx context.Context, clusterConfig *config.ClusterConfig) error
+
+	NodeList(ctx context.Context) ([]*k3dtypes.Node, error)
+	// NodesByLabel also returns nodes that are not running.
+	NodesByLabel(ctx context.Context, labels map[string]string) ([]*k3dtypes.Node, error)
+	// NodeStatuses returns the human readable status of the nodes keyed by
+	// their name. Nodes without a status are left out.
+	NodeStatuses(ctx context.Context) (map[string]string, error)
+	// NodeLogs returns the last lines of the logs of a node, including nodes
+	// that are not running.
+	NodeLogs(ctx context.Context, name string, lines int) (string, error)
+	// NodeExec runs a command in a node and returns its output.
+	NodeExec(ctx context.Context, node *k3dtypes.Node, cmd []string) (string, error)
+	// NodeImage returns the reference of the image the node was created
+	// from, as opposed to the image ID that k3d reports on the node.
+	NodeImage(ctx context.Context, node *k3dtypes.Node) (string, error)
+	// NodeDataVolumes returns the volumes holding the state of a node that
+	// have to be carried over when the node is replaced.
+	NodeDataVolumes(ctx context.Context, node *k3dtypes.Node) ([]string, error)
+	NodeReplace(ctx context.Context, node *k3dtypes.Node, replacement *k3dtypes.Node) error
+
+	LoadbalancerConfig(ctx context.Context, cluster *k3dtypes.Cluster) (k3dtypes.LoadbalancerConfig, error)
+
+	// NetworkGet returns runtimeerrors.ErrRuntimeNetworkNotExists if the
+	// network does not exist.
+	NetworkGet(ctx context.Context, name string) (*k3dtypes.ClusterNetwork, error)
+	// NetworkCreate creates a network with the settings that the k3d runtime
+	// cannot express and returns its ID. NetworkInspect returns
+	// runtimeerrors.ErrRuntimeNetworkNotExists if the network does not exist.
+	NetworkCreate(ctx context.Context, spec networkSpec) (string, error)
+	NetworkInspect(ctx context.Context, id string) (*networkDetails, error)
+	NetworkDelete(ctx context.Context, name string) error
+
+	// KubeconfigWrite merges the kubeconfig of the cluster into the default
+	// kubeconfig and KubeconfigRemove removes it again.
+	KubeconfigWrite(ctx context.Context, cluster *k3dtypes.Cluster) error
+	KubeconfigRemove(ctx context.Context, cluster *k3dtypes.Cluster) error
+
+	FreePort() (int, error)
+	// HostPortInUse reports whether the port binding is already taken on
+	// the host that the runtime binds ports on.
+	HostPortInUse(binding nat.PortBinding) bool
+}
+
+// networkSpec describes a network to create. The runtime picks the addresses
+// that are left empty.
+type networkSpec struct {
+	Name   string
+	Labels map[string]string
+	// IPAM holds one entry per address family, an IPv6 entry makes the
+	// network dual-stack
+	IPAM []networkIPAM
+}
+
+// networkIPAM is the address configuration of a network for one address
+// family.
+type networkIPAM struct {
+	Subnet  string
+	Gateway string
+	IPRange string
+}
+
+// networkDetails is a network as reported by the runtime, including the
+// labels k3d adds to all of its networks.
+type networkDetails struct {
+	ID     string
+	Name   string
+	Labels map[string]string
+	IPAM   []networkIPAM
+}
+
+// k3dClient implements k3dRuntime with the k3d client and the selected k3d
+// runtime.
+type k3dClient struct{}
+
+var _ k3dRuntime = k3dClient{}
+
+func (k3dClient) ClusterGet(ctx context.Context, name string) (*k3dtypes.Cluster, error) {
+	defer withK3dLogContext(ctx)()
+
+	return client.ClusterGet(ctx, runtimes.SelectedRuntime, &k3dtypes.Cluster{Name: name})
+}
+
+func (k3dClient) ClusterRun(ctx context.Context, clusterConfig *config.ClusterConfig) error {
+	defer withK3dLogContext(ctx)()
+
+	return client.ClusterRun(ctx, runtimes.SelectedRuntime, clusterConfig)
+}
+
+func (k3dClient) ClusterDelete(ctx context.Context, cluster *k3dtypes.Cluster, opts k3dtypes.ClusterDeleteOpts) error {
+	defer withK3dLogContext(ctx)()
+
+	return client.ClusterDelete(ctx, runtimes.SelectedRuntime, cluster, opts)
+}
+
+func (k3dClient) ClusterConfig(ctx context.Context, simpleConf config.SimpleConfig) (*config.ClusterConfig, error) {
+	defer withK3dLogContext(ctx)()
+
+	return confutils.TransformSimpleToClusterConfig(ctx, runtimes.SelectedRuntime, simpleConf)
+}
+
+func (k3dClient) ClusterConfigValidate(ctx context.Context, clusterConfig *config.ClusterConfig) error {
+	defer withK3dLogContext(ctx)()
+
+	return confutils.ValidateClusterConfig(ctx, runtimes.SelectedRuntime, *clusterConfig)
+}
+
+func (k3dClient) NodeList(ctx context.Context) ([]*k3dtypes.Node, error) {
+	defer withK3dLogContext(ctx)()
+
+	return client.NodeList(ctx, runtimes.SelectedRuntime)
+}
+
+func (k3dClient) NodesByLabel(ctx context.Context, labels map[string]string) ([]*k3dtypes.Node, error) {
+	defer withK3dLogContext(ctx)()
+
+	return runtimes.SelectedRuntime.GetNodesByLabel(ctx, labels)
+}
+
+// NodeStatuses lists the containers through the docker API, as the k3d
+// runtime interface only reports the container state.
+func (k3dClient) NodeStatuses(ctx context.Context) (map[string]string, error) {
+	statuses := make(map[string]string)
+
+	if runtimes.SelectedRuntime.ID() != runtimes.Docker.ID() {
+		return statuses, nil
+	}
+
+	docker, err := dockerruntime.GetDockerClient()
+	if err != nil {
+		return statuses, err
+	}
+	defer docker.Close()
+
+	args := filters.NewArgs()
+	for k, v := range k3dtypes.DefaultRuntimeLabels {
+		args.Add("label", fmt.Sprintf("%s=%s", k, v))
+	}
+
+	containers, err := docker.ContainerList(ctx, container.ListOptions{All: true, Filters: args})
+	if err != nil {
+		return statuses, err
+	}
+
+	for _, c := range containers {
+		for _, name := range c.Names {
+			statuses[strings.TrimPrefix(name, "/")] = c.Status
+		}
+	}
+
+	return statuses, nil
+}
+
+// NodeLogs reads the logs through the docker API, as the k3d runtime only
+// returns the logs of running nodes.
+func (k3dClient) NodeLogs(ctx context.Context, name string, lines int) (string, error) {
+	docker, err := dockerruntime.GetDockerClient()
+	if err != nil {
+		return "", fmt.Errorf("failed to create docker client: %w", err)
+	}
+	defer docker.Close()
+
+	logs, err := docker.ContainerLogs(ctx, name, dockertypes.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       strconv.Itoa(lines),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read logs of node %s: %w", name, err)
+	}
+	defer logs.Close()
+
+	var buf bytes.Buffer
+	if _, err := stdcopy.StdCopy(&buf, &buf, logs); err != nil {
+		return "", fmt.Errorf("failed to read logs of node %s: %w", name, err)
+	}
+
+	return buf.String(), nil
+}
+
+func (k3dClient) NodeExec(ctx context.Context, node *k3dtypes.Node, cmd []string) (string, error) {
+	defer withK3dLogContext(ctx)()
+
+	logs, err := runtimes.SelectedRuntime.ExecInNodeGetLogs(ctx, node, cmd)
+
+	var output string
+	if logs != nil {
+		out, _ := io.ReadAll(logs)
+		output = string(out)
+	}
+
+	return output, err
+}
+
+// NodeImage inspects the container through the docker API, as the k3d
+// runtime only reports the ID of the image.
+func (k3dClient) NodeImage(ctx context.Context, node *k3dtypes.Node) (string, error) {
+	if runtimes.SelectedRuntime.ID() != runtimes.Docker.ID() {
+		return node.Image, nil
+	}
+
+	docker, err := dockerruntime.GetDockerClient()
+	if err != nil {
+		return "", fmt.Errorf("failed to create docker client: %w", err)
+	}
+	defer docker.Close()
+
+	container, err := docker.ContainerInspect(ctx, node.Name)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect node %s: %w", node.Name, err)
+	}
+
+	if container.Config == nil {
+		return "", fmt.Errorf("node %s has no container config", node.Name)
+	}
+
+	return container.Config.Image, nil
+}
+
+// NodeDataVolumes returns volume specifications (`name:destination`) for the
+// runtime volumes mounted into the node that are not already part of the
+// node's configured volumes. These are the anonymous volumes declared by the
+// K3s image, which hold the datastore and kubelet state.
+func (k3dClient) NodeDataVolumes(ctx context.Context, node *k3dtypes.Node) ([]string, error) {
+	if runtimes.SelectedRuntime.ID() != runtimes.Docker.ID() {
+		tflog.Warn(ctx, fmt.Sprintf("not preserving data volumes of node %s with the %s runtime", node.Name, runtimes.SelectedRuntime.ID()))
+		return nil, nil
+	}
+
+	docker, err := dockerruntime.GetDockerClient()
+	if err != nil {
+		return nil, err
+	}
+	defer docker.Close()
+
+	container, err := docker.ContainerInspect(ctx, node.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	configured := make(map[string]struct{})
+	for _, volume := range node.Volumes {
+		parts := strings.Split(volume, ":")
+		if len(parts) > 1 {
+			configured[parts[1]] = struct{}{}
+		}
+	}
+
+	var volumes []string
+	for _, mnt := range container.Mounts {
+		if mnt.Type != mount.TypeVolume || mnt.Name == "" {
+			continue
+		}
+
+		if _, ok := configured[mnt.Destination]; ok {
+			continue
+		}
+
+		volumes = append(volumes, fmt.Sprintf("%s:%s", mnt.Name, mnt.Destination))
+	}
+
+	return volumes, nil
+}
+
+func (k3dClient) NodeReplace(ctx context.Context, node *k3dtypes.Node, replacement *k3dtypes.Node) error {
+	defer withK3dLogContext(ctx)()
+
+	return client.NodeReplace(ctx, runtimes.SelectedRuntime, node, replacement)
+}
+
+func (k3dClient) LoadbalancerConfig(ctx context.Context, cluster *k3dtypes.Cluster) (k3dtypes.LoadbalancerConfig, error) {
+	defer withK3dLogContext(ctx)()
+
+	return client.GetLoadbalancerConfig(ctx, runtimes.SelectedRuntime, cluster)
+}
+
+func (k3dClient) NetworkGet(ctx context.Context, name string) (*k3dtypes.ClusterNetwork, error) {
+	defer withK3dLogContext(ctx)()
+
+	return runtimes.SelectedRuntime.GetNetwork(ctx, &k3dtypes.ClusterNetwork{Name: name})
+}
+
+// NetworkCreate creates the network through the docker API, as the k3d
+// runtime has no notion of network labels or IPAM settings beyond the subnet.
+// The network gets the same defaults k3d applies to its own networks.
+func (k3dClient) NetworkCreate(ctx context.Context, spec networkSpec) (string, error) {
+	labels := make(map[string]string)
+	for k, v := range k3dtypes.DefaultRuntimeLabels {
+		labels[k] = v
+	}
+	for k, v := range spec.Labels {
+		labels[k] = v
+	}
+
+	opts := dockertypes.NetworkCreate{
+		Driver: "bridge",
+		Options: map[string]string{
+			"com.docker.network.bridge.enable_ip_masquerade": "true",
+		},
+		CheckDuplicate: true,
+		Labels:         labels,
+	}
+
+	if len(spec.IPAM) > 0 {
+		opts.IPAM = &network.IPAM{}
+	}
+	for _, ipam := range spec.IPAM {
+		if prefix, err := netip.ParsePrefix(ipam.Subnet); err == nil && prefix.Addr().Is6() {
+			opts.EnableIPv6 = true
+		}
+
+		opts.IPAM.Config = append(opts.IPAM.Config, network.IPAMConfig{
+			Subnet:  ipam.Subnet,
+			Gateway: ipam.Gateway,
+			IPRange: ipam.IPRange,
+		})
+	}
+
+	docker, err := dockerruntime.GetDockerClient()
+	if err != nil {
+		return "", fmt.Errorf("failed to create docker client: %w", err)
+	}
+	defer docker.Close()
+
+	created, err := docker.NetworkCreate(ctx, spec.Name, opts)
+	if err != nil {
+		return "", err
+	}
+
+	return created.ID, nil
+}
+
+// NetworkInspect reads the network through the docker API, as the k3d
+// runtime only reports the first subnet of a network and not its labels.
+func (k3dClient) NetworkInspect(ctx context.Context, id string) (*networkDetails, error) {
+	docker, err := dockerruntime.GetDockerClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	defer docker.Close()
+
+	inspected, err := docker.NetworkInspect(ctx, id, dockertypes.NetworkInspectOptions{})
+	if err != nil {
+		if dockerclient.IsErrNotFound(err) {
+			return nil, runtimeerrors.ErrRuntimeNetworkNotExists
+		}
+		return nil, err
+	}
+
+	details := &networkDetails{
+		ID:     inspected.ID,
+		Name:   inspected.Name,
+		Labels: inspected.Labels,
+	}
+	for _, config := range inspected.IPAM.Config {
+		details.IPAM = append(details.IPAM, networkIPAM{
+			Subnet:  config.Subnet,
+			Gateway: config.Gateway,
+			IPRange: config.IPRange,
+		})
+	}
+
+	return details, nil
+}
+
+func (k3dClient) NetworkDelete(ctx context.Context, name string) error {
+	defer withK3dLogContext(ctx)()
+
+	return runtimes.SelectedRuntime.DeleteNetwork(ctx, name)
+}
+
+func (k3dClient) KubeconfigWrite(ctx context.Context, cluster *k3dtypes.Cluster) error {
+	defer withK3dLogContext(ctx)()
+
+	return writeKubeconfig(ctx, cluster)
+}
+
+// KubeconfigRemove removes the cluster from the default kubeconfig as well as
+// the standalone kubeconfig file that k3d may have written for it.
+func (k3dClient) KubeconfigRemove(ctx context.Context, cluster *k3dtypes.Cluster) error {
+	defer withK3dLogContext(ctx)()
+
+	if err := client.KubeconfigRemoveClusterFromDefaultConfig(ctx, cluster); err != nil {
+		return fmt.Errorf("failed to remove kubeconfig from default config: %w", err)
+	}
+
+	configDir, err := k3dutil.GetConfigDirOrCreate()
+	if err != nil {
+		return fmt.Errorf("failed to delete kubeconfig file: %w", err)
+	}
+
+	kubeconfigfile := filepath.Join(configDir, fmt.Sprintf("kubeconfig-%s.yaml", cluster.Name))
+	if err := os.Remove(kubeconfigfile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete kubeconfig file '%s': %w", kubeconfigfile, err)
+	}
+
+	return nil
+}
+
+func (k3dClient) FreePort() (int, error) {
+	return k3dutil.GetFreePort()
+}
+
+// HostPortInUse only checks the ports of this host if the runtime binds its
+// ports here.
+func (k3dClient) HostPortInUse(binding nat.PortBinding) bool {
+	return localRuntime() && hostPortInUse(binding)
+}
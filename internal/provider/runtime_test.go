@@ -0,0 +1,579 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/docker/go-connections/nat"
+	client "github.com/k3d-io/k3d/v5/pkg/client"
+	confutils "github.com/k3d-io/k3d/v5/pkg/config"
+	config "github.com/k3d-io/k3d/v5/pkg/config/v1alpha5"
+	runtimeerrors "github.com/k3d-io/k3d/v5/pkg/runtimes/errors"
+	k3dtypes "github.com/k3d-io/k3d/v5/pkg/types"
+)
+
+// fakeRuntime is an in-memory k3dRuntime. It records the clusters and
+// networks created through it and reports them back the way k3d does, so the
+// resources can be tested without a container runtime.
+type fakeRuntime struct {
+	mu sync.Mutex
+
+	clusters map[string]*k3dtypes.Cluster
+	networks map[string]*k3dtypes.ClusterNetwork
+	// networkDetails are the networks created through NetworkCreate
+	networkDetails map[string]*networkDetails
+	// images are the references the nodes were created from
+	images map[string]string
+	// replacements counts how often the nodes have been replaced
+	replacements map[string]int
+	// logs are returned for the nodes with the same name
+	logs map[string]string
+	// failures are returned by the methods with the same name
+	failures    map[string]error
+	kubeconfigs map[string]struct{}
+	nextPort    int
+}
+
+var _ k3dRuntime = &fakeRuntime{}
+
+func newFakeRuntime() *fakeRuntime {
+	return &fakeRuntime{
+		clusters:       make(map[string]*k3dtypes.Cluster),
+		networks:       make(map[string]*k3dtypes.ClusterNetwork),
+		networkDetails: make(map[string]*networkDetails),
+		images:         make(map[string]string),
+		replacements:   make(map[string]int),
+		logs:           make(map[string]string),
+		failures:       make(map[string]error),
+		kubeconfigs:    make(map[string]struct{}),
+		nextPort:       40000,
+	}
+}
+
+// failOn makes the method with the given name return the error.
+func (f *fakeRuntime) failOn(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.failures[method] = err
+}
+
+// setLogs sets the logs returned for the node.
+func (f *fakeRuntime) setLogs(node string, logs string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.logs[node] = logs
+}
+
+// addCluster records a cluster as if it had been created outside of
+// Terraform.
+func (f *fakeRuntime) addCluster(t *testing.T, data k3dClusterData) {
+	t.Helper()
+
+	clusterConfig, diags := buildClusterConfig(context.Background(), f, &data)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	if err := f.ClusterRun(context.Background(), clusterConfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// removeCluster removes a cluster and its network as if it had been deleted
+// outside of Terraform.
+func (f *fakeRuntime) removeCluster(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if cluster, ok := f.clusters[name]; ok {
+		delete(f.networks, cluster.Network.Name)
+		delete(f.networkDetails, cluster.Network.Name)
+		delete(f.clusters, name)
+	}
+}
+
+// removeNode removes a node from its cluster as if it had been deleted
+// outside of Terraform.
+func (f *fakeRuntime) removeNode(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, cluster := range f.clusters {
+		for i, node := range cluster.Nodes {
+			if node.Name == name {
+				cluster.Nodes = append(cluster.Nodes[:i:i], cluster.Nodes[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// cluster returns a copy of the recorded cluster, or nil if there is none.
+func (f *fakeRuntime) cluster(name string) *k3dtypes.Cluster {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cluster, ok := f.clusters[name]
+	if !ok {
+		return nil
+	}
+
+	return copyCluster(cluster)
+}
+
+// checkEmpty reports the clusters, networks and kubeconfigs that are left.
+func (f *fakeRuntime) checkEmpty() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.clusters) > 0 || len(f.networks) > 0 || len(f.kubeconfigs) > 0 {
+		return fmt.Errorf("expected no clusters, networks and kubeconfigs, got %d clusters, %d networks and %d kubeconfigs", len(f.clusters), len(f.networks), len(f.kubeconfigs))
+	}
+
+	return nil
+}
+
+// fakeImageID returns a stable image ID for an image reference, as k3d
+// reports the ID of the image on the nodes rather than the reference.
+func fakeImageID(image string) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(normalizeImage(image))))
+}
+
+func copyCluster(cluster *k3dtypes.Cluster) *k3dtypes.Cluster {
+	c := *cluster
+	c.Nodes = make([]*k3dtypes.Node, 0, len(cluster.Nodes))
+	for _, node := range cluster.Nodes {
+		n := *node
+		c.Nodes = append(c.Nodes, &n)
+	}
+
+	return &c
+}
+
+func (f *fakeRuntime) ClusterGet(ctx context.Context, name string) (*k3dtypes.Cluster, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.failures["ClusterGet"]; err != nil {
+		return nil, err
+	}
+
+	cluster, ok := f.clusters[name]
+	if !ok || len(cluster.Nodes) == 0 {
+		return nil, client.ClusterGetNoNodesFoundError
+	}
+
+	return copyCluster(cluster), nil
+}
+
+// ClusterConfig transforms the configuration the way k3d does, except that
+// release channels resolve to the image k3d was built with instead of being
+// looked up over the network. The transformation only uses the runtime for
+// port mappings, which the provider does not configure.
+func (f *fakeRuntime) ClusterConfig(ctx context.Context, simpleConf config.SimpleConfig) (*config.ClusterConfig, error) {
+	if isK3sChannelImage(simpleConf.Image) {
+		simpleConf.Image = k3sDefaultImage()
+	}
+
+	return confutils.TransformSimpleToClusterConfig(ctx, nil, simpleConf)
+}
+
+// ClusterConfigValidate validates the configuration the way k3d does. The
+// validation only uses the runtime for named volumes, which the provider
+// does not configure.
+func (f *fakeRuntime) ClusterConfigValidate(ctx context.Context, clusterConfig *config.ClusterConfig) error {
+	return confutils.ValidateClusterConfig(ctx, nil, *clusterConfig)
+}
+
+// ClusterRun records the cluster with the labels that k3d sets on the nodes.
+// A failing run records the nodes as stopped, like k3d leaves them before
+// they are rolled back.
+func (f *fakeRuntime) ClusterRun(ctx context.Context, clusterConfig *config.ClusterConfig) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cluster := &clusterConfig.Cluster
+	if _, ok := f.clusters[cluster.Name]; ok {
+		return fmt.Errorf("cluster %q already exists", cluster.Name)
+	}
+
+	if _, ok := f.networks[cluster.Network.Name]; ok {
+		cluster.Network.External = true
+	} else {
+		prefix := cluster.Network.IPAM.IPPrefix
+		if !prefix.IsValid() {
+			prefix = f.freePrefix()
+		}
+
+		f.networks[cluster.Network.Name] = &k3dtypes.ClusterNetwork{
+			Name: cluster.Network.Name,
+			IPAM: k3dtypes.IPAM{IPPrefix: prefix},
+		}
+	}
+
+	if cluster.Token == "" {
+		cluster.Token = "fake-token"
+	}
+
+	if cluster.KubeAPI.Binding.HostPort == "" {
+		cluster.KubeAPI.Binding.HostPort = strconv.Itoa(f.nextPort)
+		f.nextPort++
+	}
+
+	labels := map[string]string{
+		k3dtypes.LabelClusterName: cluster.Name,
+	}
+	if len(clusterConfig.ClusterCreateOpts.HostAliases) > 0 {
+		hostAliases, err := json.Marshal(clusterConfig.ClusterCreateOpts.HostAliases)
+		if err != nil {
+			return err
+		}
+		labels[k3dtypes.LabelClusterStartHostAliases] = string(hostAliases)
+	}
+
+	// the first address of the network is its gateway
+	ip := f.networks[cluster.Network.Name].IPAM.IPPrefix.Addr().Next()
+	for _, other := range f.clusters {
+		if other.Network.Name == cluster.Network.Name {
+			for range other.Nodes {
+				ip = ip.Next()
+			}
+		}
+	}
+
+	err := f.failures["ClusterRun"]
+
+	for _, node := range cluster.Nodes {
+		ip = ip.Next()
+		node.IP = k3dtypes.NodeIP{IP: ip}
+		node.RuntimeLabels = make(map[string]string)
+		for k, v := range k3dtypes.DefaultRuntimeLabels {
+			node.RuntimeLabels[k] = v
+		}
+		for k, v := range labels {
+			node.RuntimeLabels[k] = v
+		}
+		node.RuntimeLabels[k3dtypes.LabelRole] = string(node.Role)
+
+		if node.Role == k3dtypes.ServerRole {
+			node.ServerOpts.KubeAPI = cluster.KubeAPI
+			node.RuntimeLabels[k3dtypes.LabelServerAPIHostIP] = cluster.KubeAPI.Binding.HostIP
+			node.RuntimeLabels[k3dtypes.LabelServerAPIPort] = cluster.KubeAPI.Binding.HostPort
+		}
+
+		f.images[node.Name] = node.Image
+		node.Image = fakeImageID(node.Image)
+
+		node.Networks = []string{cluster.Network.Name}
+		node.Created = "2024-01-01T00:00:00Z"
+		node.State = k3dtypes.NodeState{Running: err == nil, Status: "running"}
+		if err != nil {
+			node.State.Status = "exited"
+		}
+	}
+
+	if cluster.ServerLoadBalancer != nil && cluster.ServerLoadBalancer.Node != nil {
+		cluster.ServerLoadBalancer.Node.Ports = nat.PortMap{
+			k3dtypes.DefaultAPIPort + "/tcp": []nat.PortBinding{cluster.KubeAPI.Binding},
+		}
+	}
+
+	f.clusters[cluster.Name] = copyCluster(cluster)
+
+	return err
+}
+
+func (f *fakeRuntime) ClusterDelete(ctx context.Context, cluster *k3dtypes.Cluster, opts k3dtypes.ClusterDeleteOpts) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.failures["ClusterDelete"]; err != nil {
+		return err
+	}
+
+	delete(f.clusters, cluster.Name)
+
+	if !cluster.Network.External {
+		delete(f.networks, cluster.Network.Name)
+	}
+
+	return nil
+}
+
+func (f *fakeRuntime) NodeList(ctx context.Context) ([]*k3dtypes.Node, error) {
+	return f.NodesByLabel(ctx, nil)
+}
+
+func (f *fakeRuntime) NodesByLabel(ctx context.Context, labels map[string]string) ([]*k3dtypes.Node, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.failures["NodeList"]; err != nil {
+		return nil, err
+	}
+
+	var nodes []*k3dtypes.Node
+	for _, cluster := range f.clusters {
+	next:
+		for _, node := range copyCluster(cluster).Nodes {
+			for k, v := range labels {
+				if node.RuntimeLabels[k] != v {
+					continue next
+				}
+			}
+			nodes = append(nodes, node)
+		}
+	}
+
+	sortNodesByName(nodes)
+
+	return nodes, nil
+}
+
+func (f *fakeRuntime) NodeStatuses(ctx context.Context) (map[string]string, error) {
+	nodes, err := f.NodeList(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]string)
+	for _, node := range nodes {
+		if node.State.Running {
+			statuses[node.Name] = "Up 5 minutes"
+		} else {
+			statuses[node.Name] = "Exited (1) 5 minutes ago"
+		}
+	}
+
+	return statuses, nil
+}
+
+func (f *fakeRuntime) NodeLogs(ctx context.Context, name string, lines int) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.logs[name], nil
+}
+
+// NodeExec answers the kubectl queries for the Ready condition of a node
+// with a heartbeat that changes whenever the node is replaced.
+func (f *fakeRuntime) NodeExec(ctx context.Context, node *k3dtypes.Node, cmd []string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.failures["NodeExec"]; err != nil {
+		return "", err
+	}
+
+	if len(cmd) > 3 && cmd[0] == "kubectl" && cmd[1] == "get" && cmd[2] == "node" {
+		return fmt.Sprintf("True 2024-01-01T00:%02d:00Z", f.replacements[cmd[3]]), nil
+	}
+
+	return "", nil
+}
+
+func (f *fakeRuntime) NodeImage(ctx context.Context, node *k3dtypes.Node) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	image, ok := f.images[node.Name]
+	if !ok {
+		return "", fmt.Errorf("node %s not found", node.Name)
+	}
+
+	return image, nil
+}
+
+func (f *fakeRuntime) NodeDataVolumes(ctx context.Context, node *k3dtypes.Node) ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeRuntime) NodeReplace(ctx context.Context, node *k3dtypes.Node, replacement *k3dtypes.Node) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.failures["NodeReplace"]; err != nil {
+		return err
+	}
+
+	for _, cluster := range f.clusters {
+		for i, n := range cluster.Nodes {
+			if n.Name == node.Name {
+				r := *replacement
+				r.State = k3dtypes.NodeState{Running: true, Status: "running"}
+				f.images[r.Name] = r.Image
+				r.Image = fakeImageID(r.Image)
+				cluster.Nodes[i] = &r
+				f.replacements[r.Name]++
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("node %s not found", node.Name)
+}
+
+func (f *fakeRuntime) LoadbalancerConfig(ctx context.Context, cluster *k3dtypes.Cluster) (k3dtypes.LoadbalancerConfig, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.clusters[cluster.Name]
+	if !ok || c.ServerLoadBalancer == nil || c.ServerLoadBalancer.Config == nil {
+		return k3dtypes.LoadbalancerConfig{}, fmt.Errorf("cluster %q has no load balancer", cluster.Name)
+	}
+
+	return *c.ServerLoadBalancer.Config, nil
+}
+
+func (f *fakeRuntime) NetworkGet(ctx context.Context, name string) (*k3dtypes.ClusterNetwork, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	network, ok := f.networks[name]
+	if !ok {
+		return nil, runtimeerrors.ErrRuntimeNetworkNotExists
+	}
+
+	n := *network
+	return &n, nil
+}
+
+// freePrefix returns an IPv4 subnet that is not used by another network.
+func (f *fakeRuntime) freePrefix() netip.Prefix {
+	return netip.MustParsePrefix(fmt.Sprintf("172.%d.0.0/16", 18+len(f.networks)))
+}
+
+// NetworkCreate records the network with the addresses docker would pick.
+func (f *fakeRuntime) NetworkCreate(ctx context.Context, spec networkSpec) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.failures["NetworkCreate"]; err != nil {
+		return "", err
+	}
+
+	if _, ok := f.networks[spec.Name]; ok {
+		return "", fmt.Errorf("network with name %s already exists", spec.Name)
+	}
+
+	details := &networkDetails{
+		ID:     fmt.Sprintf("%x", sha256.Sum256([]byte(spec.Name))),
+		Name:   spec.Name,
+		Labels: make(map[string]string),
+	}
+	for k, v := range k3dtypes.DefaultRuntimeLabels {
+		details.Labels[k] = v
+	}
+	for k, v := range spec.Labels {
+		details.Labels[k] = v
+	}
+
+	ipam := spec.IPAM
+	if len(ipam) == 0 {
+		ipam = []networkIPAM{{Subnet: f.freePrefix().String()}}
+	}
+
+	var prefix netip.Prefix
+	for _, config := range ipam {
+		p, err := netip.ParsePrefix(config.Subnet)
+		if err != nil {
+			return "", err
+		}
+
+		// the first address of the subnet is its gateway
+		if config.Gateway == "" {
+			config.Gateway = p.Addr().Next().String()
+		}
+		if p.Addr().Is4() {
+			prefix = p
+		}
+
+		details.IPAM = append(details.IPAM, config)
+	}
+
+	f.networks[spec.Name] = &k3dtypes.ClusterNetwork{
+		Name: spec.Name,
+		ID:   details.ID,
+		IPAM: k3dtypes.IPAM{IPPrefix: prefix},
+	}
+	f.networkDetails[spec.Name] = details
+
+	return details.ID, nil
+}
+
+func (f *fakeRuntime) NetworkInspect(ctx context.Context, id string) (*networkDetails, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, details := range f.networkDetails {
+		if details.ID == id || details.Name == id {
+			d := *details
+			return &d, nil
+		}
+	}
+
+	return nil, runtimeerrors.ErrRuntimeNetworkNotExists
+}
+
+// NetworkDelete deletes a network by its name or ID, like docker does.
+func (f *fakeRuntime) NetworkDelete(ctx context.Context, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, network := range f.networks {
+		if network.ID != "" && network.ID == name {
+			name = network.Name
+		}
+	}
+
+	for _, cluster := range f.clusters {
+		if cluster.Network.Name == name {
+			return runtimeerrors.ErrRuntimeNetworkNotEmpty
+		}
+	}
+
+	delete(f.networks, name)
+	delete(f.networkDetails, name)
+
+	return nil
+}
+
+func (f *fakeRuntime) KubeconfigWrite(ctx context.Context, cluster *k3dtypes.Cluster) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.kubeconfigs[cluster.Name] = struct{}{}
+
+	return nil
+}
+
+func (f *fakeRuntime) KubeconfigRemove(ctx context.Context, cluster *k3dtypes.Cluster) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.kubeconfigs, cluster.Name)
+
+	return nil
+}
+
+func (f *fakeRuntime) FreePort() (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	port := f.nextPort
+	f.nextPort++
+
+	return port, nil
+}
+
+func (f *fakeRuntime) HostPortInUse(binding nat.PortBinding) bool {
+	return false
+}
@@ -3,24 +3,46 @@ package provider
 import (
 	"context"
 	"fmt"
-	"net"
+	"net/netip"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework-validators/helpers/validatordiag"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
 var (
-	validatePort = &portValidator{}
-	validateIP   = &ipValidator{}
+	validatePort      = &portValidator{}
+	validateAPIPort   = &portValidator{allowRandom: true}
+	validateIP        = &ipValidator{}
+	validateIPv4      = &ipValidator{family: ipFamilyV4}
+	validateIPv6      = &ipValidator{family: ipFamilyV6}
+	validateHostIP    = &ipValidator{brackets: true}
+	validateHost      = &hostValidator{}
+	validateCIDR      = &cidrValidator{}
+	validateIPv4CIDR  = &cidrValidator{family: ipFamilyV4}
+	validateIPv6CIDR  = &cidrValidator{family: ipFamilyV6}
+	validateDualStack = &dualStackValidator{}
+	validateDuration  = &durationValidator{}
+	validateRegex     = &regexValidator{}
 )
 
-type portValidator struct{}
+type portValidator struct {
+	// allowRandom accepts 0 for a port that is picked when the port is bound.
+	allowRandom bool
+}
 
 // Description describes the validation in plain text formatting.
 //
 // This information may be automatically added to schema plain text
 // descriptions by external tooling.
 func (v *portValidator) Description(context.Context) string {
+	if v.allowRandom {
+		return "A valid port in the range of 1-65535, or 0 to pick a free port"
+	}
+
 	return "A valid port in the range of 1-65535"
 }
 
@@ -28,8 +50,12 @@ func (v *portValidator) Description(context.Context) string {
 //
 // This information may be automatically added to schema Markdown
 // descriptions by external tooling.
-func (v *portValidator) MarkdownDescription(context.Context) string {
-	return "A valid port in the range of 1-65535"
+func (v *portValidator) MarkdownDescription(ctx context.Context) string {
+	if v.allowRandom {
+		return "A valid port in the range of 1-65535, or `0` to pick a free port"
+	}
+
+	return v.Description(ctx)
 }
 
 // Validate performs the validation.
@@ -40,6 +66,10 @@ func (v *portValidator) ValidateInt64(ctx context.Context, req validator.Int64Re
 
 	port := req.ConfigValue.ValueInt64()
 
+	if port == 0 && v.allowRandom {
+		return
+	}
+
 	if port < 1 || port > 65535 {
 		resp.Diagnostics.Append(validatordiag.InvalidAttributeValueDiagnostic(
 			req.Path,
@@ -51,14 +81,57 @@ func (v *portValidator) ValidateInt64(ctx context.Context, req validator.Int64Re
 	}
 }
 
-type ipValidator struct{}
+// ipFamily restricts the IP address family accepted by a validator.
+type ipFamily int
+
+const (
+	ipFamilyAny ipFamily = iota
+	ipFamilyV4
+	ipFamilyV6
+)
+
+func (f ipFamily) matches(addr netip.Addr) bool {
+	switch f {
+	case ipFamilyV4:
+		return addr.Is4()
+	case ipFamilyV6:
+		return addr.Is6() && !addr.Is4In6()
+	default:
+		return true
+	}
+}
+
+func (f ipFamily) String() string {
+	switch f {
+	case ipFamilyV4:
+		return "IPv4"
+	case ipFamilyV6:
+		return "IPv6"
+	default:
+		return "IPv4 or IPv6"
+	}
+}
+
+type ipValidator struct {
+	family ipFamily
+	// brackets allows IPv6 addresses to be enclosed in square brackets as
+	// they are in URLs and host:port pairs (e.g. `[::1]`).
+	brackets bool
+}
 
 func (v *ipValidator) Description(context.Context) string {
-	return "A valid IPv4 address in dotted-quad notation"
+	switch {
+	case v.family == ipFamilyV4:
+		return "A valid IPv4 address in dotted-quad notation"
+	case v.brackets:
+		return fmt.Sprintf("A valid %s address. IPv6 addresses may be enclosed in square brackets", v.family)
+	default:
+		return fmt.Sprintf("A valid %s address", v.family)
+	}
 }
 
-func (v *ipValidator) MarkdownDescription(context.Context) string {
-	return "A valid IPv4 address in dotted-quad notation"
+func (v *ipValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
 }
 
 func (v *ipValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
@@ -68,7 +141,14 @@ func (v *ipValidator) ValidateString(ctx context.Context, req validator.StringRe
 
 	ip := req.ConfigValue.ValueString()
 
-	if net.ParseIP(ip) == nil {
+	// only IPv6 addresses may be enclosed in brackets
+	value := ip
+	if inner := unbracketIP(ip); v.brackets && inner != ip && strings.Contains(inner, ":") {
+		value = inner
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil || addr.Zone() != "" || !v.family.matches(addr) {
 		resp.Diagnostics.Append(validatordiag.InvalidAttributeValueDiagnostic(
 			req.Path,
 			v.Description(ctx),
@@ -78,3 +158,216 @@ func (v *ipValidator) ValidateString(ctx context.Context, req validator.StringRe
 		return
 	}
 }
+
+var (
+	// hostLabelRegex matches a single label of an RFC 1123 hostname.
+	hostLabelRegex = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?$`)
+	// numericLabelRegex matches a label made of digits only.
+	numericLabelRegex = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// hostValidator validates an RFC 1123 hostname or an IP address, as accepted
+// by the host part of a URL.
+type hostValidator struct{}
+
+func (v *hostValidator) Description(context.Context) string {
+	return "A valid RFC 1123 hostname or IP address. IPv6 addresses may be enclosed in square brackets"
+}
+
+func (v *hostValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v *hostValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsUnknown() || req.ConfigValue.IsNull() {
+		return
+	}
+
+	host := req.ConfigValue.ValueString()
+
+	if !validHost(host) {
+		resp.Diagnostics.Append(validatordiag.InvalidAttributeValueDiagnostic(
+			req.Path,
+			v.Description(ctx),
+			host,
+		))
+
+		return
+	}
+}
+
+func validHost(host string) bool {
+	if inner := unbracketIP(host); inner != host {
+		addr, err := netip.ParseAddr(inner)
+		return err == nil && addr.Is6() && addr.Zone() == ""
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.Zone() == ""
+	}
+
+	if len(host) > 253 {
+		return false
+	}
+
+	labels := strings.Split(host, ".")
+	for _, label := range labels {
+		if len(label) > 63 || !hostLabelRegex.MatchString(label) {
+			return false
+		}
+	}
+
+	// a name made of numeric labels only would be mistaken for an IPv4
+	// address
+	return !numericLabelRegex.MatchString(labels[len(labels)-1])
+}
+
+type cidrValidator struct {
+	family ipFamily
+}
+
+func (v *cidrValidator) Description(context.Context) string {
+	if v.family == ipFamilyAny {
+		return "A valid network prefix in CIDR notation"
+	}
+
+	return fmt.Sprintf("A valid %s network prefix in CIDR notation", v.family)
+}
+
+func (v *cidrValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v *cidrValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsUnknown() || req.ConfigValue.IsNull() {
+		return
+	}
+
+	cidr := req.ConfigValue.ValueString()
+
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil || prefix.Masked() != prefix || !v.family.matches(prefix.Addr()) {
+		resp.Diagnostics.Append(validatordiag.InvalidAttributeValueDiagnostic(
+			req.Path,
+			v.Description(ctx),
+			cidr,
+		))
+
+		return
+	}
+}
+
+// dualStackValidator validates a list of network prefixes in CIDR notation
+// that configures either a single stack or both an IPv4 and an IPv6 prefix,
+// as expected by K3s for its cluster and service CIDRs.
+type dualStackValidator struct{}
+
+func (v *dualStackValidator) Description(context.Context) string {
+	return "Either a single network prefix or one IPv4 and one IPv6 network prefix in CIDR notation"
+}
+
+func (v *dualStackValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v *dualStackValidator) ValidateList(ctx context.Context, req validator.ListRequest, resp *validator.ListResponse) {
+	if req.ConfigValue.IsUnknown() || req.ConfigValue.IsNull() {
+		return
+	}
+
+	var cidrs []string
+	seen := make(map[bool]bool)
+	for _, elem := range req.ConfigValue.Elements() {
+		value, ok := elem.(types.String)
+		if !ok || value.IsUnknown() {
+			return
+		}
+		if value.IsNull() {
+			continue
+		}
+
+		cidrs = append(cidrs, value.ValueString())
+
+		prefix, err := netip.ParsePrefix(value.ValueString())
+		if err != nil {
+			// reported by the element validator
+			continue
+		}
+
+		if seen[prefix.Addr().Is4()] {
+			resp.Diagnostics.Append(validatordiag.InvalidAttributeValueDiagnostic(
+				req.Path,
+				v.Description(ctx),
+				strings.Join(cidrs, ","),
+			))
+			return
+		}
+		seen[prefix.Addr().Is4()] = true
+	}
+}
+
+// unbracketIP strips the square brackets around an IPv6 address.
+func unbracketIP(ip string) string {
+	if strings.HasPrefix(ip, "[") && strings.HasSuffix(ip, "]") {
+		return ip[1 : len(ip)-1]
+	}
+
+	return ip
+}
+
+type durationValidator struct{}
+
+func (v *durationValidator) Description(context.Context) string {
+	return "A positive duration such as 30s or 5m"
+}
+
+func (v *durationValidator) MarkdownDescription(context.Context) string {
+	return "A positive duration such as `30s` or `5m`"
+}
+
+func (v *durationValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsUnknown() || req.ConfigValue.IsNull() {
+		return
+	}
+
+	value := req.ConfigValue.ValueString()
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		resp.Diagnostics.Append(validatordiag.InvalidAttributeValueDiagnostic(
+			req.Path,
+			v.Description(ctx),
+			value,
+		))
+
+		return
+	}
+}
+
+type regexValidator struct{}
+
+func (v *regexValidator) Description(context.Context) string {
+	return "A valid RE2 regular expression"
+}
+
+func (v *regexValidator) MarkdownDescription(context.Context) string {
+	return "A valid [RE2](https://github.com/google/re2/wiki/Syntax) regular expression"
+}
+
+func (v *regexValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsUnknown() || req.ConfigValue.IsNull() {
+		return
+	}
+
+	value := req.ConfigValue.ValueString()
+
+	if _, err := regexp.Compile(value); err != nil {
+		resp.Diagnostics.Append(validatordiag.InvalidAttributeValueDiagnostic(
+			req.Path,
+			fmt.Sprintf("%s: %v", v.Description(ctx), err),
+			value,
+		))
+
+		return
+	}
+}
@@ -0,0 +1,255 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func testValidateString(t *testing.T, v validator.String, value types.String) bool {
+	t.Helper()
+
+	req := validator.StringRequest{
+		Path:        path.Root("test"),
+		ConfigValue: value,
+	}
+	resp := &validator.StringResponse{}
+
+	v.ValidateString(context.Background(), req, resp)
+
+	return !resp.Diagnostics.HasError()
+}
+
+func TestIPValidator(t *testing.T) {
+	cases := map[string]struct {
+		value                  types.String
+		any, v4, v6, bracketed bool
+	}{
+		"null":             {types.StringNull(), true, true, true, true},
+		"unknown":          {types.StringUnknown(), true, true, true, true},
+		"ipv4":             {types.StringValue("127.0.0.1"), true, true, false, true},
+		"ipv6":             {types.StringValue("::1"), true, false, true, true},
+		"ipv6 full":        {types.StringValue("fd00:10::1"), true, false, true, true},
+		"bracketed ipv6":   {types.StringValue("[::1]"), false, false, false, true},
+		"bracketed ipv4":   {types.StringValue("[127.0.0.1]"), false, false, false, false},
+		"ipv4 mapped ipv6": {types.StringValue("::ffff:127.0.0.1"), true, false, false, true},
+		"zone":             {types.StringValue("fe80::1%eth0"), false, false, false, false},
+		"hostname":         {types.StringValue("localhost"), false, false, false, false},
+		"cidr":             {types.StringValue("127.0.0.1/8"), false, false, false, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			for v, expected := range map[validator.String]bool{
+				validateIP:     tc.any,
+				validateIPv4:   tc.v4,
+				validateIPv6:   tc.v6,
+				validateHostIP: tc.bracketed,
+			} {
+				if valid := testValidateString(t, v, tc.value); valid != expected {
+					t.Errorf("%s: expected valid=%t, got %t", v.Description(context.Background()), expected, valid)
+				}
+			}
+		})
+	}
+}
+
+func TestPortValidator(t *testing.T) {
+	cases := map[string]struct {
+		value         types.Int64
+		valid, random bool
+	}{
+		"null":     {types.Int64Null(), true, true},
+		"unknown":  {types.Int64Unknown(), true, true},
+		"lowest":   {types.Int64Value(1), true, true},
+		"api":      {types.Int64Value(6443), true, true},
+		"highest":  {types.Int64Value(65535), true, true},
+		"zero":     {types.Int64Value(0), false, true},
+		"negative": {types.Int64Value(-1), false, false},
+		"too high": {types.Int64Value(65536), false, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			for v, expected := range map[validator.Int64]bool{
+				validatePort:    tc.valid,
+				validateAPIPort: tc.random,
+			} {
+				req := validator.Int64Request{
+					Path:        path.Root("test"),
+					ConfigValue: tc.value,
+				}
+				resp := &validator.Int64Response{}
+
+				v.ValidateInt64(context.Background(), req, resp)
+
+				if valid := !resp.Diagnostics.HasError(); valid != expected {
+					t.Errorf("%s: expected valid=%t, got %t", v.Description(context.Background()), expected, valid)
+				}
+			}
+		})
+	}
+}
+
+func TestHostValidator(t *testing.T) {
+	cases := map[string]struct {
+		value types.String
+		valid bool
+	}{
+		"null":              {types.StringNull(), true},
+		"unknown":           {types.StringUnknown(), true},
+		"single label":      {types.StringValue("localhost"), true},
+		"short":             {types.StringValue("k3d"), true},
+		"dotted":            {types.StringValue("k3d.local"), true},
+		"fqdn":              {types.StringValue("api.dev.example.com"), true},
+		"hyphens":           {types.StringValue("k3d-api.example.com"), true},
+		"upper case":        {types.StringValue("K3D.Local"), true},
+		"leading digit":     {types.StringValue("1api.example.com"), true},
+		"ipv4":              {types.StringValue("127.0.0.1"), true},
+		"ipv6":              {types.StringValue("::1"), true},
+		"bracketed ipv6":    {types.StringValue("[::1]"), true},
+		"bracketed ipv4":    {types.StringValue("[127.0.0.1]"), false},
+		"empty":             {types.StringValue(""), false},
+		"underscore":        {types.StringValue("k3d_api.local"), false},
+		"leading hyphen":    {types.StringValue("-api.local"), false},
+		"trailing hyphen":   {types.StringValue("api-.local"), false},
+		"empty label":       {types.StringValue("api..local"), false},
+		"trailing dot":      {types.StringValue("api.local."), false},
+		"port":              {types.StringValue("api.local:6443"), false},
+		"url":               {types.StringValue("https://api.local"), false},
+		"numeric tld":       {types.StringValue("api.123"), false},
+		"invalid ipv4":      {types.StringValue("256.0.0.1"), false},
+		"label too long":    {types.StringValue(strings.Repeat("a", 64) + ".local"), false},
+		"hostname too long": {types.StringValue(strings.Repeat(strings.Repeat("a", 63)+".", 4) + "local"), false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if valid := testValidateString(t, validateHost, tc.value); valid != tc.valid {
+				t.Errorf("expected valid=%t, got %t", tc.valid, valid)
+			}
+		})
+	}
+}
+
+func TestCIDRValidator(t *testing.T) {
+	cases := map[string]struct {
+		value       types.String
+		any, v4, v6 bool
+	}{
+		"null":          {types.StringNull(), true, true, true},
+		"unknown":       {types.StringUnknown(), true, true, true},
+		"ipv4":          {types.StringValue("172.28.0.0/16"), true, true, false},
+		"ipv6":          {types.StringValue("fd00:10::/64"), true, false, true},
+		"host bits set": {types.StringValue("172.28.0.1/16"), false, false, false},
+		"no prefix":     {types.StringValue("172.28.0.0"), false, false, false},
+		"garbage":       {types.StringValue("not-a-cidr"), false, false, false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			for v, expected := range map[validator.String]bool{
+				validateCIDR:     tc.any,
+				validateIPv4CIDR: tc.v4,
+				validateIPv6CIDR: tc.v6,
+			} {
+				if valid := testValidateString(t, v, tc.value); valid != expected {
+					t.Errorf("%s: expected valid=%t, got %t", v.Description(context.Background()), expected, valid)
+				}
+			}
+		})
+	}
+}
+
+func TestDualStackValidator(t *testing.T) {
+	list := func(values ...string) types.List {
+		return types.ListValueMust(types.StringType, func() []attr.Value {
+			elems := make([]attr.Value, 0, len(values))
+			for _, v := range values {
+				elems = append(elems, types.StringValue(v))
+			}
+			return elems
+		}())
+	}
+
+	cases := map[string]struct {
+		value types.List
+		valid bool
+	}{
+		"null":        {types.ListNull(types.StringType), true},
+		"unknown":     {types.ListUnknown(types.StringType), true},
+		"ipv4":        {list("10.42.0.0/16"), true},
+		"ipv6":        {list("fd00:42::/56"), true},
+		"dual stack":  {list("10.42.0.0/16", "fd00:42::/56"), true},
+		"ipv6 first":  {list("fd00:42::/56", "10.42.0.0/16"), true},
+		"two ipv4":    {list("10.42.0.0/16", "10.43.0.0/16"), false},
+		"two ipv6":    {list("fd00:42::/56", "fd00:43::/56"), false},
+		"unknown elt": {types.ListValueMust(types.StringType, []attr.Value{types.StringUnknown(), types.StringValue("10.42.0.0/16")}), true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := validator.ListRequest{
+				Path:        path.Root("test"),
+				ConfigValue: tc.value,
+			}
+			resp := &validator.ListResponse{}
+
+			validateDualStack.ValidateList(context.Background(), req, resp)
+
+			if valid := !resp.Diagnostics.HasError(); valid != tc.valid {
+				t.Errorf("expected valid=%t, got %t", tc.valid, valid)
+			}
+		})
+	}
+}
+
+func TestDurationValidator(t *testing.T) {
+	cases := map[string]struct {
+		value types.String
+		valid bool
+	}{
+		"null":     {types.StringNull(), true},
+		"unknown":  {types.StringUnknown(), true},
+		"minutes":  {types.StringValue("5m"), true},
+		"compound": {types.StringValue("1m30s"), true},
+		"zero":     {types.StringValue("0s"), false},
+		"negative": {types.StringValue("-1m"), false},
+		"no unit":  {types.StringValue("300"), false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if valid := testValidateString(t, validateDuration, tc.value); valid != tc.valid {
+				t.Errorf("expected valid=%t, got %t", tc.valid, valid)
+			}
+		})
+	}
+}
+
+func TestRegexValidator(t *testing.T) {
+	cases := map[string]struct {
+		value types.String
+		valid bool
+	}{
+		"null":      {types.StringNull(), true},
+		"unknown":   {types.StringUnknown(), true},
+		"literal":   {types.StringValue("k3d-test-server-0"), true},
+		"pattern":   {types.StringValue(`^k3d-.*-agent-\d+$`), true},
+		"unclosed":  {types.StringValue("k3d-(server"), false},
+		"lookahead": {types.StringValue("k3d-(?=server)"), false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if valid := testValidateString(t, validateRegex, tc.value); valid != tc.valid {
+				t.Errorf("expected valid=%t, got %t", tc.valid, valid)
+			}
+		})
+	}
+}